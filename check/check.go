@@ -2,13 +2,28 @@ package check
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/ernierasta/zorix/check/cmd"
+	"github.com/ernierasta/zorix/check/dns"
+	"github.com/ernierasta/zorix/check/file"
+	"github.com/ernierasta/zorix/check/grpc"
+	"github.com/ernierasta/zorix/check/kv"
+	"github.com/ernierasta/zorix/check/mailloop"
 	"github.com/ernierasta/zorix/check/ping"
 	"github.com/ernierasta/zorix/check/port"
+	"github.com/ernierasta/zorix/check/process"
+	"github.com/ernierasta/zorix/check/scenario"
+	"github.com/ernierasta/zorix/check/sql"
+	"github.com/ernierasta/zorix/check/ssh"
+	"github.com/ernierasta/zorix/check/starttls"
+	"github.com/ernierasta/zorix/check/system"
+	"github.com/ernierasta/zorix/check/tls"
 	"github.com/ernierasta/zorix/check/web"
+	"github.com/ernierasta/zorix/check/websocket"
 	"github.com/ernierasta/zorix/shared"
 	log "github.com/sirupsen/logrus"
 )
@@ -21,7 +36,10 @@ type Manager struct {
 	quitTickerChannels       map[string]chan bool
 	resultsChan              chan shared.CheckConfig
 	httpTimeout, pingTimeout shared.Duration
-	portTimeout              shared.Duration
+	portTimeout, dnsTimeout  shared.Duration
+	tlsTimeout, mailTimeout  shared.Duration
+	sshTimeout, sqlTimeout   shared.Duration
+	kvTimeout, grpcTimeout   shared.Duration
 }
 
 // registerWorker adds worker to requestedWorkers map.
@@ -45,12 +63,38 @@ func (cm *Manager) registerWorker(t string) {
 			cm.requestedWorkers["web"] = worker{worker: web.New(cm.httpTimeout, false), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
 		case "insecureweb":
 			cm.requestedWorkers["insecureweb"] = worker{worker: web.New(cm.httpTimeout, true), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
+		case "websocket":
+			cm.requestedWorkers["websocket"] = worker{worker: websocket.New(cm.httpTimeout), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
 		case "cmd":
 			cm.requestedWorkers["cmd"] = worker{worker: cmd.New(), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
 		case "ping":
 			cm.requestedWorkers["ping"] = worker{worker: ping.New(cm.pingTimeout), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
 		case "port":
 			cm.requestedWorkers["port"] = worker{worker: port.New(cm.portTimeout), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
+		case "dns":
+			cm.requestedWorkers["dns"] = worker{worker: dns.New(cm.dnsTimeout), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
+		case "scenario":
+			cm.requestedWorkers["scenario"] = worker{worker: scenario.New(cm.httpTimeout), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
+		case "tls":
+			cm.requestedWorkers["tls"] = worker{worker: tls.New(cm.tlsTimeout), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
+		case "starttls":
+			cm.requestedWorkers["starttls"] = worker{worker: starttls.New(cm.tlsTimeout), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
+		case "mailloop":
+			cm.requestedWorkers["mailloop"] = worker{worker: mailloop.New(cm.mailTimeout), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
+		case "ssh":
+			cm.requestedWorkers["ssh"] = worker{worker: ssh.New(cm.sshTimeout), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
+		case "system":
+			cm.requestedWorkers["system"] = worker{worker: system.New(), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
+		case "process":
+			cm.requestedWorkers["process"] = worker{worker: process.New(), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
+		case "file":
+			cm.requestedWorkers["file"] = worker{worker: file.New(), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
+		case "sql":
+			cm.requestedWorkers["sql"] = worker{worker: sql.New(cm.sqlTimeout), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
+		case "redis", "memcached":
+			cm.requestedWorkers[t] = worker{worker: kv.New(cm.kvTimeout), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
+		case "grpc":
+			cm.requestedWorkers["grpc"] = worker{worker: grpc.New(cm.grpcTimeout), typeChan: make(chan shared.CheckConfig, len(cm.checks)), checks: c}
 		default:
 			log.Fatalf("check.registerWorker: unknown worker type: '%s', check config file.", t)
 		}
@@ -72,6 +116,13 @@ func NewManager(cc shared.CMConfig) *Manager {
 		httpTimeout:        cc.HTTPTimeout,
 		pingTimeout:        cc.PingTimeout,
 		portTimeout:        cc.PortTimeout,
+		dnsTimeout:         cc.DNSTimeout,
+		tlsTimeout:         cc.TLSTimeout,
+		mailTimeout:        cc.MailTimeout,
+		sshTimeout:         cc.SSHTimeout,
+		sqlTimeout:         cc.SQLTimeout,
+		kvTimeout:          cc.KVTimeout,
+		grpcTimeout:        cc.GRPCTimeout,
 	}
 }
 
@@ -118,13 +169,71 @@ func (cm *Manager) runTicker(c shared.CheckConfig, typeChan chan shared.CheckCon
 			ticker.Stop()
 			return
 		default:
-			typeChan <- c
+			if c.FanOut {
+				for _, fc := range fanOut(c) {
+					typeChan <- fc
+				}
+			} else {
+				typeChan <- c
+			}
 			<-ticker.C
 
 		}
 	}
 }
 
+// fanOut resolves all A/AAAA records of check host and returns check
+// for every address. Every check has address pinned (see dialer resolve)
+// and own ID "ID@address", so results are processed separately.
+// If host can not be resolved, original check is returned, it will fail in worker.
+func fanOut(c shared.CheckConfig) []shared.CheckConfig {
+	host, port, err := targetHostPort(c)
+	if err != nil {
+		log.WithFields(log.Fields{"check_id": c.ID}).Errorf("check.fanOut: %v", err)
+		return []shared.CheckConfig{c}
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		log.WithFields(log.Fields{"check_id": c.ID}).Errorf("check.fanOut: can not resolve %s, err: %v", host, err)
+		return []shared.CheckConfig{c}
+	}
+
+	checks := []shared.CheckConfig{}
+	for _, ip := range ips {
+		if (c.IPVersion == 4 && ip.To4() == nil) || (c.IPVersion == 6 && ip.To4() != nil) {
+			continue
+		}
+		fc := c
+		fc.ID = c.ID + "@" + ip.String()
+		fc.Address = ip.String()
+		fc.Resolve = append([]string{net.JoinHostPort(host, port) + ":" + ip.String()}, c.Resolve...)
+		checks = append(checks, fc)
+	}
+	if len(checks) == 0 {
+		return []shared.CheckConfig{c}
+	}
+	return checks
+}
+
+// targetHostPort returns host and port checked by web or port check.
+func targetHostPort(c shared.CheckConfig) (string, string, error) {
+	if c.Type == "port" {
+		return net.SplitHostPort(c.Check)
+	}
+	u, err := url.Parse(c.Check)
+	if err != nil {
+		return "", "", err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return u.Hostname(), port, nil
+}
+
 // worker is helper type, every worker type has its own implementation,
 // job channel and amount of checks processed by this type of worker.
 type worker struct {
@@ -138,7 +247,17 @@ type worker struct {
 func startWorker(id string, w shared.Worker, typeChan, resultsChan chan shared.CheckConfig) {
 	log.WithFields(log.Fields{"worker_id": id, "chan": typeChan}).Info("starting some work ...")
 	for c := range typeChan {
-		code, body, time, err := w.Send(c)
+		var (
+			code int
+			body string
+			time int64
+			err  error
+		)
+		if vw, ok := w.(shared.VarsWorker); ok {
+			code, body, time, c.Vars, err = vw.SendVars(c)
+		} else {
+			code, body, time, err = w.Send(c)
+		}
 		c.ReturnedCode = code
 		c.ReturnedTime = time
 		c.Response = body
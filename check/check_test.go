@@ -0,0 +1,43 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+func Test_targetHostPort(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        shared.CheckConfig
+		wantHost string
+		wantPort string
+	}{
+		{"http", shared.CheckConfig{Type: "web", Check: "http://www.google.com/x"}, "www.google.com", "80"},
+		{"https", shared.CheckConfig{Type: "insecureweb", Check: "https://www.google.com"}, "www.google.com", "443"},
+		{"explicit port", shared.CheckConfig{Type: "web", Check: "https://www.google.com:8443/"}, "www.google.com", "8443"},
+		{"port", shared.CheckConfig{Type: "port", Check: "google.com:22"}, "google.com", "22"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, err := targetHostPort(tt.c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if host != tt.wantHost || port != tt.wantPort {
+				t.Errorf("targetHostPort() = %v, %v, want %v, %v", host, port, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
+
+func Test_fanOut(t *testing.T) {
+	c := shared.CheckConfig{ID: "local", Type: "port", Check: "127.0.0.1:22"}
+	checks := fanOut(c)
+	if len(checks) != 1 {
+		t.Fatalf("fanOut() returned %d checks, want 1", len(checks))
+	}
+	if checks[0].ID != "local@127.0.0.1" || checks[0].Address != "127.0.0.1" || checks[0].Resolve[0] != "127.0.0.1:22:127.0.0.1" {
+		t.Errorf("fanOut() = %+v", checks[0])
+	}
+}
@@ -0,0 +1,251 @@
+// Package dialer prepares network connections for workers, based
+// on check network settings: proxy, IP version, source address
+// and pinned host addresses.
+package dialer
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+	"golang.org/x/net/proxy"
+)
+
+// Dialer dials connections respecting check network settings.
+type Dialer struct {
+	dialer    net.Dialer
+	ipVersion int
+	source    net.IP
+	proxy     *url.URL
+	proxyTLS  *tls.Config // for https proxy
+	resolve   map[string]string
+}
+
+// New returns Dialer for given check. Proxy should be already
+// normalized in config (global proxy is used if check has none).
+func New(c *shared.CheckConfig, timeout time.Duration) (*Dialer, error) {
+	d := &Dialer{
+		dialer:    net.Dialer{Timeout: timeout},
+		ipVersion: c.IPVersion,
+	}
+
+	if c.Source != "" {
+		ip, err := sourceIP(c.Source, c.IPVersion)
+		if err != nil {
+			return nil, fmt.Errorf("dialer.New: wrong source %q, err: %v", c.Source, err)
+		}
+		if d.ipVersion == 0 {
+			d.ipVersion = 6
+			if ip.To4() != nil {
+				d.ipVersion = 4
+			}
+		}
+		d.source = ip
+	}
+
+	if len(c.Resolve) > 0 {
+		d.resolve = map[string]string{}
+		for _, r := range c.Resolve {
+			hostPort, ip, err := shared.ParseResolve(r)
+			if err != nil {
+				return nil, fmt.Errorf("dialer.New: %v", err)
+			}
+			d.resolve[hostPort] = ip
+		}
+	}
+
+	if c.Proxy != "" && !c.NoProxy {
+		u, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("dialer.New: wrong proxy %q, err: %v", c.Proxy, err)
+		}
+		d.proxy = u
+		d.proxyTLS = &tls.Config{ServerName: u.Hostname()}
+	}
+	return d, nil
+}
+
+// DialContext dials address directly (without proxy), using forced
+// IP version and source address. It can be used in http.Transport.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	address = d.resolved(address)
+	dialer := d.dialer
+	if d.source != nil {
+		switch network {
+		case "udp", "udp4", "udp6":
+			dialer.LocalAddr = &net.UDPAddr{IP: d.source}
+		default:
+			dialer.LocalAddr = &net.TCPAddr{IP: d.source}
+		}
+	}
+	return dialer.DialContext(ctx, d.network(network), address)
+}
+
+// Dial dials address, through proxy if configured.
+// HTTP and HTTPS proxies are used via CONNECT method, SOCKS5 natively.
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	if d.proxy == nil {
+		return d.DialContext(context.Background(), network, address)
+	}
+	switch d.proxy.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if d.proxy.User != nil {
+			pass, _ := d.proxy.User.Password()
+			auth = &proxy.Auth{User: d.proxy.User.Username(), Password: pass}
+		}
+		p, err := proxy.SOCKS5("tcp", d.proxy.Host, auth, direct{d})
+		if err != nil {
+			return nil, err
+		}
+		return p.Dial(network, d.resolved(address))
+	case "http", "https":
+		return d.connect(d.resolved(address))
+	}
+	return nil, fmt.Errorf("dialer.Dial: unsupported proxy scheme %q", d.proxy.Scheme)
+}
+
+// direct is proxy.Dialer used for connection to proxy itself.
+type direct struct {
+	d *Dialer
+}
+
+func (dd direct) Dial(network, address string) (net.Conn, error) {
+	return dd.d.DialContext(context.Background(), network, address)
+}
+
+// Proxy returns proxy function for http.Transport, nil if proxy is not used.
+func (d *Dialer) Proxy() func(*http.Request) (*url.URL, error) {
+	if d.proxy == nil {
+		return nil
+	}
+	return http.ProxyURL(d.proxy)
+}
+
+// connect opens tunnel to address using HTTP CONNECT method.
+// Connection to https proxy is encrypted (TLS to proxy).
+func (d *Dialer) connect(address string) (net.Conn, error) {
+	conn, err := d.DialContext(context.Background(), "tcp", d.proxy.Host)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(d.dialer.Timeout))
+	if d.proxy.Scheme == "https" {
+		tlsConn := tls.Client(conn, d.proxyTLS)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("tls handshake with proxy %s failed, err: %v", d.proxy.Host, err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+	if d.proxy.User != nil {
+		pass, _ := d.proxy.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(d.proxy.User.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT to %s returned %s", address, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	if br.Buffered() > 0 {
+		// target data (f.e.: smtp banner) came with proxy response
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is connection, which reads data buffered
+// while reading proxy response first.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (bc *bufferedConn) Read(b []byte) (int, error) {
+	return bc.r.Read(b)
+}
+
+// resolved returns address with host replaced by pinned IP,
+// if address is in resolve overrides.
+func (d *Dialer) resolved(address string) string {
+	if ip, ok := d.resolve[strings.ToLower(address)]; ok {
+		_, port, _ := net.SplitHostPort(address)
+		return net.JoinHostPort(ip, port)
+	}
+	return address
+}
+
+// network returns network with forced IP version (f.e.: tcp4).
+func (d *Dialer) network(network string) string {
+	switch d.ipVersion {
+	case 4, 6:
+		if network == "tcp" || network == "udp" || network == "ip" {
+			return fmt.Sprintf("%s%d", network, d.ipVersion)
+		}
+	}
+	return network
+}
+
+// sourceIP returns IP address from source, which can be IP address
+// or interface name. For interface first address matching IP version
+// is used (IPv4 is preferred if version is not set).
+func sourceIP(source string, ipVersion int) (net.IP, error) {
+	if ip := net.ParseIP(source); ip != nil {
+		return ip, nil
+	}
+	iface, err := net.InterfaceByName(source)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	var v4, v6 net.IP
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipnet.IP.To4() != nil && v4 == nil {
+			v4 = ipnet.IP
+		} else if ipnet.IP.To4() == nil && v6 == nil {
+			v6 = ipnet.IP
+		}
+	}
+	switch {
+	case ipVersion == 6 && v6 != nil:
+		return v6, nil
+	case ipVersion != 6 && v4 != nil:
+		return v4, nil
+	case ipVersion == 0 && v6 != nil:
+		return v6, nil
+	}
+	return nil, fmt.Errorf("interface %s has no usable address", source)
+}
@@ -0,0 +1,195 @@
+package dialer
+
+import (
+	"bufio"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+func TestDialer_network(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       shared.CheckConfig
+		network string
+		want    string
+	}{
+		{"any", shared.CheckConfig{}, "tcp", "tcp"},
+		{"ipv4", shared.CheckConfig{IPVersion: 4}, "tcp", "tcp4"},
+		{"ipv6 udp", shared.CheckConfig{IPVersion: 6}, "udp", "udp6"},
+		{"already forced", shared.CheckConfig{IPVersion: 6}, "tcp4", "tcp4"},
+		{"from source", shared.CheckConfig{Source: "127.0.0.1"}, "tcp", "tcp4"},
+		{"from ipv6 source", shared.CheckConfig{Source: "::1"}, "tcp", "tcp6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := New(&tt.c, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := d.network(tt.network); got != tt.want {
+				t.Errorf("Dialer.network() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_proxy(t *testing.T) {
+	d, err := New(&shared.CheckConfig{Proxy: "socks5://127.0.0.1:1080", NoProxy: true}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Proxy() != nil {
+		t.Errorf("New() proxy used, but no_proxy is set")
+	}
+}
+
+func TestDialer_resolved(t *testing.T) {
+	d, err := New(&shared.CheckConfig{Resolve: []string{"example.com:443:10.0.0.1"}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.resolved("Example.com:443"); got != "10.0.0.1:443" {
+		t.Errorf("Dialer.resolved() = %v, want 10.0.0.1:443", got)
+	}
+	if got := d.resolved("example.com:80"); got != "example.com:80" {
+		t.Errorf("Dialer.resolved() = %v, want example.com:80", got)
+	}
+}
+
+func TestDialer_Dial_proxy(t *testing.T) {
+	// target echoes first line
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				conn.Write([]byte(line))
+			}()
+		}
+	}()
+
+	connect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "CONNECT" || r.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte("zorix:secret")) {
+			http.Error(w, "proxy auth required", http.StatusProxyAuthRequired)
+			return
+		}
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			target.Close()
+			return
+		}
+		go func() {
+			io.Copy(target, buf)
+			target.Close()
+		}()
+		io.Copy(conn, target)
+		conn.Close()
+	})
+	httpProxy := httptest.NewServer(connect)
+	defer httpProxy.Close()
+	httpsProxy := httptest.NewTLSServer(connect)
+	defer httpsProxy.Close()
+
+	tests := []struct {
+		name    string
+		proxy   string
+		wantErr bool
+	}{
+		{"http", strings.Replace(httpProxy.URL, "http://", "http://zorix:secret@", 1), false},
+		{"https", strings.Replace(httpsProxy.URL, "https://", "https://zorix:secret@", 1), false},
+		{"https wrong credentials", strings.Replace(httpsProxy.URL, "https://", "https://zorix:wrong@", 1), true},
+		{"https proxy as http", strings.Replace(httpsProxy.URL, "https://", "http://zorix:secret@", 1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := New(&shared.CheckConfig{Proxy: tt.proxy}, 2*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.proxyTLS.RootCAs = x509.NewCertPool()
+			d.proxyTLS.RootCAs.AddCert(httpsProxy.Certificate())
+			conn, err := d.Dial("tcp", l.Addr().String())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Dialer.Dial() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(2 * time.Second))
+			conn.Write([]byte("ping\n"))
+			if got, _ := bufio.NewReader(conn).ReadString('\n'); got != "ping\n" {
+				t.Errorf("Dialer.Dial() tunnel returned %q, want ping", got)
+			}
+		})
+	}
+}
+
+func TestDialer_Dial_proxyBanner(t *testing.T) {
+	// proxy sends CONNECT response and target banner in one write
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil || line == "\r\n" {
+				break
+			}
+		}
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n220 mail.example.com ESMTP\r\n"))
+		line, _ := r.ReadString('\n')
+		conn.Write([]byte("250 " + line))
+	}()
+
+	d, err := New(&shared.CheckConfig{Proxy: "http://" + l.Addr().String()}, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := d.Dial("tcp", "mail.example.com:25")
+	if err != nil {
+		t.Fatalf("Dialer.Dial() error = %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	r := bufio.NewReader(conn)
+	if got, _ := r.ReadString('\n'); got != "220 mail.example.com ESMTP\r\n" {
+		t.Errorf("Dialer.Dial() banner = %q, want 220 banner", got)
+	}
+	conn.Write([]byte("EHLO zorix\r\n"))
+	if got, _ := r.ReadString('\n'); got != "250 EHLO zorix\r\n" {
+		t.Errorf("Dialer.Dial() reply = %q, want 250", got)
+	}
+}
@@ -0,0 +1,194 @@
+// Package dns implements native DNS resolution worker.
+// It queries chosen resolver (or system one) and compares
+// answer with expected records.
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+const (
+	defaultPort = "53"
+)
+
+// DNS worker
+type DNS struct {
+	timeout shared.Duration
+}
+
+// New return new DNS worker instance
+func New(timeout shared.Duration) *DNS {
+	return &DNS{timeout}
+}
+
+// Send queries resolver for c.Record records of c.Check domain.
+// Answer has to match expected records exactly (unexpected records
+// can be hijacked or stale ones), with answer_subset it has to contain them.
+// Returns returnCode, answer (one record per line), query latency and error.
+// For convince success returns code 200 and errors:
+//   - domain not found: 404
+//   - lookup error or answer not matching expected: 500
+func (d *DNS) Send(c shared.CheckConfig) (int, string, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout.Duration)
+	defer cancel()
+
+	r := resolver(c.Resolver, d.timeout.Duration)
+
+	t0 := time.Now()
+	record := strings.ToUpper(c.Record)
+	answer, err := lookup(ctx, r, record, c.Check)
+	duration := time.Since(t0).Nanoseconds() / 1000 / 1000
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			return 404, "", duration, fmt.Errorf("dns.Send: %s record for %s not found, err: %v", c.Record, c.Check, err)
+		}
+		return 500, "", duration, fmt.Errorf("dns.Send: %s lookup for %s failed, err: %v", c.Record, c.Check, err)
+	}
+
+	body := strings.Join(answer, "\n")
+	if err := checkAnswer(record, answer, c.ExpectedAnswer, c.AnswerSubset); err != nil {
+		return 500, body, duration, fmt.Errorf("dns.Send: %s answer for %s %v, got: %s", c.Record, c.Check, err, strings.Join(answer, ", "))
+	}
+
+	return 200, body, duration, nil
+}
+
+// resolver returns net.Resolver using given server (host or host:port).
+// If server is empty, system resolver is used.
+func resolver(server string, timeout time.Duration) *net.Resolver {
+	if server == "" {
+		return &net.Resolver{}
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, defaultPort)
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{Timeout: timeout}
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// lookup returns normalized records of given type.
+// MX records are returned as hosts only, preference is ignored.
+// Host without CNAME record returns not found error (resolver
+// returns host itself as canonical name).
+func lookup(ctx context.Context, r *net.Resolver, record, host string) ([]string, error) {
+	answer := []string{}
+	switch record {
+	case "A", "AAAA":
+		network := "ip4"
+		if record == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, host)
+		if err != nil {
+			return answer, err
+		}
+		for _, ip := range ips {
+			answer = append(answer, ip.String())
+		}
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, host)
+		if err != nil {
+			return answer, err
+		}
+		if normalize(cname) == normalize(host) {
+			return answer, &net.DNSError{Err: "no CNAME record", Name: host, IsNotFound: true}
+		}
+		answer = append(answer, normalize(cname))
+	case "MX":
+		mxs, err := r.LookupMX(ctx, host)
+		if err != nil {
+			return answer, err
+		}
+		for _, mx := range mxs {
+			answer = append(answer, normalize(mx.Host))
+		}
+	case "TXT":
+		txts, err := r.LookupTXT(ctx, host)
+		if err != nil {
+			return answer, err
+		}
+		answer = append(answer, txts...)
+	case "NS":
+		nss, err := r.LookupNS(ctx, host)
+		if err != nil {
+			return answer, err
+		}
+		for _, ns := range nss {
+			answer = append(answer, normalize(ns.Host))
+		}
+	default:
+		return answer, fmt.Errorf("unsupported record type %q", record)
+	}
+	sort.Strings(answer)
+	return answer, nil
+}
+
+// checkAnswer compares answer with expected records. If subset is false,
+// answer can not contain other records. Nothing is checked if expected is empty.
+func checkAnswer(record string, answer, expected []string, subset bool) error {
+	if len(expected) == 0 {
+		return nil
+	}
+	if missing := missingRecords(record, answer, expected); len(missing) > 0 {
+		return fmt.Errorf("does not contain: %s", strings.Join(missing, ", "))
+	}
+	if subset {
+		return nil
+	}
+	if unexpected := missingRecords(record, expected, answer); len(unexpected) > 0 {
+		return fmt.Errorf("contains unexpected: %s", strings.Join(unexpected, ", "))
+	}
+	return nil
+}
+
+// missingRecords returns expected records, which are not in answer.
+func missingRecords(record string, answer, expected []string) []string {
+	missing := []string{}
+	for _, e := range expected {
+		found := false
+		for _, a := range answer {
+			if equal(record, a, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, e)
+		}
+	}
+	return missing
+}
+
+// equal compares records of given type. IP addresses are compared
+// parsed (f.e.: 2001:DB8::1 and 2001:db8:0::1 are equal), TXT exactly
+// and host names case insensitive and without trailing dot.
+func equal(record, a, b string) bool {
+	switch record {
+	case "A", "AAAA":
+		ipA, ipB := net.ParseIP(strings.TrimSpace(a)), net.ParseIP(strings.TrimSpace(b))
+		if ipA == nil || ipB == nil {
+			return a == b
+		}
+		return ipA.Equal(ipB)
+	case "TXT":
+		return a == b
+	}
+	return normalize(a) == normalize(b)
+}
+
+// normalize lowercases host name and removes trailing dot
+func normalize(s string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), ".")
+}
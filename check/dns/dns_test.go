@@ -0,0 +1,152 @@
+package dns
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func Test_missingRecords(t *testing.T) {
+	type args struct {
+		record   string
+		answer   []string
+		expected []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{"nothing expected", args{"A", []string{"1.2.3.4"}, nil}, []string{}},
+		{"all found", args{"A", []string{"1.2.3.4", "5.6.7.8"}, []string{"5.6.7.8"}}, []string{}},
+		{"host with dot", args{"MX", []string{"mx.example.com"}, []string{"MX.Example.com."}}, []string{}},
+		{"missing", args{"A", []string{"1.2.3.4"}, []string{"1.2.3.4", "5.6.7.8"}}, []string{"5.6.7.8"}},
+		{"empty answer", args{"A", []string{}, []string{"1.2.3.4"}}, []string{"1.2.3.4"}},
+		{"ipv6 other form", args{"AAAA", []string{"2001:db8::1"}, []string{"2001:DB8:0::1"}}, []string{}},
+		{"txt exact", args{"TXT", []string{"v=spf1 -all"}, []string{"v=spf1 -all"}}, []string{}},
+		{"txt case sensitive", args{"TXT", []string{"key=AbC"}, []string{"key=abc"}}, []string{"key=abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missingRecords(tt.args.record, tt.args.answer, tt.args.expected); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("missingRecords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkAnswer(t *testing.T) {
+	answer := []string{"1.2.3.4", "6.6.6.6"}
+	tests := []struct {
+		name     string
+		expected []string
+		subset   bool
+		wantErr  bool
+	}{
+		{"nothing expected", nil, false, false},
+		{"exact", []string{"6.6.6.6", "1.2.3.4"}, false, false},
+		{"unexpected record", []string{"1.2.3.4"}, false, true},
+		{"missing record", []string{"1.2.3.4", "6.6.6.6", "5.6.7.8"}, false, true},
+		{"subset", []string{"1.2.3.4"}, true, false},
+		{"subset missing", []string{"5.6.7.8"}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkAnswer("A", answer, tt.expected, tt.subset); (err != nil) != tt.wantErr {
+				t.Errorf("checkAnswer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// dnsServer starts udp dns server answering A queries with given ips.
+func dnsServer(t *testing.T, ips ...string) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var p dnsmessage.Parser
+			h, err := p.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			q, err := p.Question()
+			if err != nil {
+				continue
+			}
+			b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: h.ID, Response: true, RecursionAvailable: true})
+			b.StartQuestions()
+			b.Question(q)
+			b.StartAnswers()
+			if q.Type == dnsmessage.TypeA {
+				for _, ip := range ips {
+					a := dnsmessage.AResource{}
+					copy(a.A[:], net.ParseIP(ip).To4())
+					b.AResource(dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}, a)
+				}
+			}
+			msg, err := b.Finish()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(msg, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDNS_Send(t *testing.T) {
+	resolver := dnsServer(t, "1.2.3.4", "6.6.6.6")
+	tests := []struct {
+		name     string
+		expected []string
+		subset   bool
+		wantCode int
+		wantErr  bool
+	}{
+		{"no answer given", nil, false, 200, false},
+		{"exact", []string{"1.2.3.4", "6.6.6.6"}, false, 200, false},
+		{"unexpected record", []string{"1.2.3.4"}, false, 500, true},
+		{"subset", []string{"1.2.3.4"}, true, 200, false},
+		{"subset missing", []string{"5.6.7.8"}, true, 500, true},
+	}
+	d := New(shared.Duration{Duration: 2 * time.Second})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := shared.CheckConfig{Check: "example.com", Record: "A", Resolver: resolver, ExpectedAnswer: tt.expected, AnswerSubset: tt.subset}
+			code, body, _, err := d.Send(c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DNS.Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if code != tt.wantCode {
+				t.Errorf("DNS.Send() code = %d, want %d", code, tt.wantCode)
+			}
+			if body != "1.2.3.4\n6.6.6.6" {
+				t.Errorf("DNS.Send() body = %q, want both records", body)
+			}
+		})
+	}
+}
+
+func TestDNS_Send_noCNAME(t *testing.T) {
+	// host has only A records, resolver returns host itself as canonical name
+	resolver := dnsServer(t, "1.2.3.4")
+	d := New(shared.Duration{Duration: 2 * time.Second})
+	c := shared.CheckConfig{Check: "example.com", Record: "CNAME", Resolver: resolver}
+	code, _, _, err := d.Send(c)
+	if err == nil || code != 404 {
+		t.Errorf("DNS.Send() = %d, %v, want 404 and no CNAME error", code, err)
+	}
+}
@@ -0,0 +1,214 @@
+// Package file implements file freshness worker. It checks age and size
+// of file (or newest file matching glob) and optionally verifies
+// checksum and gzip/tar archive integrity (f.e.: backups).
+package file
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+// Hashes are supported checksum algorithms.
+var Hashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// File worker
+type File struct{}
+
+// New return new File worker instance
+func New() *File {
+	return &File{}
+}
+
+// Send checks file.
+// Returns returnCode, file info, check time and error.
+func (f *File) Send(c shared.CheckConfig) (int, string, int64, error) {
+	code, body, duration, _, err := f.SendVars(c)
+	return code, body, duration, err
+}
+
+// SendVars checks file c.Check, if it is glob, newest matching file is used.
+// Returns returnCode, file info, check time, vars {file}, {age}, {age_seconds},
+// {size} and error.
+// For convince success returns code 200 and errors:
+//   - file not found: 404
+//   - older than max_age, smaller than min_size: 500
+//   - checksum mismatch, broken archive, read error: 500
+func (f *File) SendVars(c shared.CheckConfig) (int, string, int64, map[string]string, error) {
+	t0 := time.Now()
+	path, info, err := newest(c.Check)
+	if err != nil {
+		return 500, "", ms(t0), nil, fmt.Errorf("file.Send: %v", err)
+	}
+	if info == nil {
+		return 404, "", ms(t0), nil, fmt.Errorf("file.Send: %s not found", c.Check)
+	}
+
+	age := time.Since(info.ModTime())
+	vars := map[string]string{
+		"file":        path,
+		"age":         formatAge(age),
+		"age_seconds": strconv.FormatInt(int64(age.Seconds()), 10),
+		"size":        strconv.FormatInt(info.Size(), 10),
+	}
+	body := fmt.Sprintf("file: %s\nage: %s\nsize: %d", path, vars["age"], info.Size())
+
+	if c.MaxAge.Duration > 0 && age > c.MaxAge.Duration {
+		return 500, body, ms(t0), vars, fmt.Errorf("file.Send: %s is %s old, %d bytes, max age: %s", path, vars["age"], info.Size(), c.MaxAge.Duration)
+	}
+	if info.Size() < c.MinSize {
+		return 500, body, ms(t0), vars, fmt.Errorf("file.Send: %s is %s old, %d bytes, min size: %d bytes", path, vars["age"], info.Size(), c.MinSize)
+	}
+	if c.Checksum != "" {
+		sum, err := verifyChecksum(path, c.Checksum)
+		if sum != "" {
+			vars["checksum"] = sum
+		}
+		if err != nil {
+			return 500, body, ms(t0), vars, fmt.Errorf("file.Send: %s: %v", path, err)
+		}
+	}
+	if c.VerifyArchive {
+		if err := verifyArchive(path); err != nil {
+			return 500, body, ms(t0), vars, fmt.Errorf("file.Send: %s: broken archive, err: %v", path, err)
+		}
+	}
+	return 200, body, ms(t0), vars, nil
+}
+
+// newest returns newest regular file matching pattern.
+// If nothing matches, info is nil.
+func newest(pattern string) (string, os.FileInfo, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", nil, err
+	}
+	var path string
+	var info os.FileInfo
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		if info == nil || fi.ModTime().After(info.ModTime()) {
+			path, info = p, fi
+		}
+	}
+	return path, info, nil
+}
+
+// verifyChecksum compares file hash with expected one. Checksum is
+// "algorithm:hex" or just "algorithm", then expected hash is read from
+// sidecar file path.algorithm (sha256sum format). Returns file hash.
+func verifyChecksum(path, checksum string) (string, error) {
+	algo, want := checksum, ""
+	if i := strings.Index(checksum, ":"); i != -1 {
+		algo, want = checksum[:i], checksum[i+1:]
+	}
+	newHash, ok := Hashes[strings.ToLower(algo)]
+	if !ok {
+		return "", fmt.Errorf("unknown checksum algorithm %q", algo)
+	}
+	if want == "" {
+		b, err := ioutil.ReadFile(path + "." + strings.ToLower(algo))
+		if err != nil {
+			return "", fmt.Errorf("can not read checksum file, err: %v", err)
+		}
+		if f := strings.Fields(string(b)); len(f) > 0 {
+			want = f[0]
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := newHash()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(sum, want) {
+		return sum, fmt.Errorf("%s checksum is %s, expected: %s", algo, sum, want)
+	}
+	return sum, nil
+}
+
+// verifyArchive reads whole gzip stream (verifies CRC) and/or all tar
+// headers. Type is detected from content, not from file name.
+func verifyArchive(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	compressed := false
+	if magic, _ := r.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = bufio.NewReader(gz)
+		compressed = true
+	}
+	if header, err := r.Peek(512); err == nil && string(header[257:262]) == "ustar" {
+		tr := tar.NewReader(r)
+		for {
+			_, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+	} else if !compressed {
+		return fmt.Errorf("not a gzip or tar archive")
+	}
+	// gzip checksum is verified at the end of stream
+	_, err = io.Copy(ioutil.Discard, r)
+	return err
+}
+
+// formatAge returns rounded age, f.e.: "31h", "2h5m", "45s".
+func formatAge(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	s := d.Round(time.Minute).String()
+	s = strings.TrimSuffix(s, "0s")
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// ms returns time since t in whole milliseconds.
+func ms(t time.Time) int64 {
+	return time.Since(t).Nanoseconds() / 1000 / 1000
+}
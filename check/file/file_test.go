@@ -0,0 +1,123 @@
+package file
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+// tarGz returns gzipped tar with one file.
+func tarGz(t *testing.T) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	content := []byte("SELECT 1;\n")
+	if err := tw.WriteHeader(&tar.Header{Name: "dump.sql", Mode: 0644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(content)
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestFile_SendVars(t *testing.T) {
+	dir := t.TempDir()
+	archive := tarGz(t)
+	sum := sha256.Sum256(archive)
+	files := []struct {
+		name    string
+		content []byte
+		age     time.Duration
+	}{
+		{"backup-1.tar.gz", archive, 48 * time.Hour},
+		{"backup-2.tar.gz", archive, time.Hour},
+		{"backup-2.tar.gz.sha256", []byte(hex.EncodeToString(sum[:]) + "  backup-2.tar.gz\n"), time.Hour},
+		{"broken.tar.gz", archive[:len(archive)-10], time.Hour},
+		{"empty.log", []byte{}, 31 * time.Hour},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := ioutil.WriteFile(path, f.content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := time.Now().Add(-f.age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	day := shared.Duration{Duration: 24 * time.Hour}
+
+	tests := []struct {
+		name     string
+		c        shared.CheckConfig
+		wantCode int
+		wantErr  bool
+		wantFile string
+		wantAge  string
+	}{
+		{"newest of glob", shared.CheckConfig{Check: filepath.Join(dir, "backup-*.tar.gz"), MaxAge: day, MinSize: 10}, 200, false, "backup-2.tar.gz", "1h"},
+		{"too old", shared.CheckConfig{Check: filepath.Join(dir, "backup-1.tar.gz"), MaxAge: day}, 500, true, "backup-1.tar.gz", "48h"},
+		{"too small", shared.CheckConfig{Check: filepath.Join(dir, "empty.log"), MinSize: 1}, 500, true, "empty.log", "31h"},
+		{"missing", shared.CheckConfig{Check: filepath.Join(dir, "*.zip")}, 404, true, "", ""},
+		{"checksum file", shared.CheckConfig{Check: filepath.Join(dir, "backup-2.tar.gz"), Checksum: "sha256"}, 200, false, "backup-2.tar.gz", "1h"},
+		{"checksum", shared.CheckConfig{Check: filepath.Join(dir, "backup-2.tar.gz"), Checksum: "sha256:" + hex.EncodeToString(sum[:])}, 200, false, "backup-2.tar.gz", "1h"},
+		{"wrong checksum", shared.CheckConfig{Check: filepath.Join(dir, "backup-2.tar.gz"), Checksum: "sha256:abc"}, 500, true, "backup-2.tar.gz", "1h"},
+		{"archive", shared.CheckConfig{Check: filepath.Join(dir, "backup-2.tar.gz"), VerifyArchive: true}, 200, false, "backup-2.tar.gz", "1h"},
+		{"broken archive", shared.CheckConfig{Check: filepath.Join(dir, "broken.tar.gz"), VerifyArchive: true}, 500, true, "broken.tar.gz", "1h"},
+		{"not archive", shared.CheckConfig{Check: filepath.Join(dir, "backup-2.tar.gz.sha256"), VerifyArchive: true}, 500, true, "backup-2.tar.gz.sha256", "1h"},
+	}
+	f := New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _, vars, err := f.SendVars(tt.c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("File.SendVars() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if code != tt.wantCode {
+				t.Errorf("File.SendVars() code = %d, want %d", code, tt.wantCode)
+			}
+			if filepath.Base(vars["file"]) != filepath.Base(tt.wantFile) || vars["age"] != tt.wantAge {
+				t.Errorf("File.SendVars() file = %s, age = %s, want %s, %s", vars["file"], vars["age"], tt.wantFile, tt.wantAge)
+			}
+		})
+	}
+}
+
+func TestHashes(t *testing.T) {
+	// config validates checksum by shared list
+	for _, algo := range shared.FileHashes {
+		if _, ok := Hashes[algo]; !ok {
+			t.Errorf("Hashes does not support %q", algo)
+		}
+	}
+	if len(Hashes) != len(shared.FileHashes) {
+		t.Errorf("Hashes has %d algorithms, shared.FileHashes %d", len(Hashes), len(shared.FileHashes))
+	}
+}
+
+func Test_formatAge(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{45 * time.Second, "45s"},
+		{2*time.Minute + 10*time.Second, "2m"},
+		{2*time.Hour + 5*time.Minute, "2h5m"},
+		{31*time.Hour + 20*time.Second, "31h"},
+	}
+	for _, tt := range tests {
+		if got := formatAge(tt.d); got != tt.want {
+			t.Errorf("formatAge(%s) = %s, want %s", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package grpc implements gRPC health checking protocol worker
+// (grpc.health.v1.Health/Check).
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ernierasta/zorix/check/web"
+	"github.com/ernierasta/zorix/shared"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPC worker
+type GRPC struct {
+	timeout shared.Duration
+}
+
+// New return new GRPC worker instance
+func New(timeout shared.Duration) *GRPC {
+	return &GRPC{timeout}
+}
+
+// Send calls health Check for c.Service (empty means whole server).
+// Returns returnCode, serving status, call time and error.
+func (g *GRPC) Send(c shared.CheckConfig) (int, string, int64, error) {
+	code, body, duration, _, err := g.SendVars(c)
+	return code, body, duration, err
+}
+
+// SendVars calls health Check for c.Service (empty means whole server).
+// Returns returnCode, serving status, call time, {status} var and error.
+// For convince success (SERVING) returns code 200 and errors:
+//   - NOT_SERVING: 503
+//   - UNKNOWN, SERVICE_UNKNOWN: 500
+//   - service not found: 404
+//   - health service not implemented: 501
+//   - transport and other errors: 500
+func (g *GRPC) SendVars(c shared.CheckConfig) (int, string, int64, map[string]string, error) {
+	creds := insecure.NewCredentials()
+	if c.TLS {
+		tlsConfig, err := web.TLSConfig(&c)
+		if err != nil {
+			return 500, "", 0, nil, fmt.Errorf("grpc.Send: %v", err)
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	conn, err := grpc.NewClient(c.Check, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return 500, "", 0, nil, fmt.Errorf("grpc.Send: can not create client for %s, err: %v", c.Check, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout.Duration)
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, md(c.Headers))
+
+	t0 := time.Now()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: c.Service})
+	duration := time.Since(t0).Nanoseconds() / 1000 / 1000
+	if err != nil {
+		s := status.Convert(err)
+		vars := map[string]string{"status": s.Code().String()}
+		return errCode(s.Code()), "", duration, vars, fmt.Errorf("grpc.Send: health check %s failed, err: %v", name(c), err)
+	}
+
+	st := resp.GetStatus()
+	vars := map[string]string{"status": st.String()}
+	switch st {
+	case healthpb.HealthCheckResponse_SERVING:
+		return 200, st.String(), duration, vars, nil
+	case healthpb.HealthCheckResponse_NOT_SERVING:
+		return 503, st.String(), duration, vars, fmt.Errorf("grpc.Send: %s is %s", name(c), st)
+	}
+	return 500, st.String(), duration, vars, fmt.Errorf("grpc.Send: %s is %s", name(c), st)
+}
+
+// md returns metadata from headers in config format ("Name: value" lines).
+func md(headers string) metadata.MD {
+	m := metadata.MD{}
+	for k, vv := range web.ParseHeaders(headers) {
+		m.Append(strings.ToLower(k), vv...)
+	}
+	return m
+}
+
+// errCode maps gRPC error code to response code.
+func errCode(c codes.Code) int {
+	switch c {
+	case codes.NotFound:
+		return 404
+	case codes.Unimplemented:
+		return 501
+	}
+	return 500
+}
+
+// name returns checked service for messages.
+func name(c shared.CheckConfig) string {
+	if c.Service == "" {
+		return c.Check
+	}
+	return c.Check + " service " + c.Service
+}
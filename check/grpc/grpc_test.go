@@ -0,0 +1,58 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestGRPC_Send(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotToken string
+	s := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (interface{}, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md["x-token"]) > 0 {
+			gotToken = md["x-token"][0]
+		}
+		return h(ctx, req)
+	}))
+	hs := health.NewServer()
+	hs.SetServingStatus("api", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("db", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(s, hs)
+	go s.Serve(l)
+	defer s.Stop()
+
+	tests := []struct {
+		name     string
+		service  string
+		wantCode int
+	}{
+		{"server", "", 200},
+		{"serving", "api", 200},
+		{"not serving", "db", 503},
+		{"unknown service", "missing", 404},
+	}
+	g := New(shared.Duration{Duration: 5 * time.Second})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := shared.CheckConfig{Check: l.Addr().String(), Service: tt.service, Headers: "X-Token: abc"}
+			code, _, _, err := g.Send(c)
+			if code != tt.wantCode {
+				t.Errorf("GRPC.Send() code = %d, want %d, err: %v", code, tt.wantCode, err)
+			}
+		})
+	}
+	if gotToken != "abc" {
+		t.Errorf("GRPC.Send() metadata x-token = %q, want abc", gotToken)
+	}
+}
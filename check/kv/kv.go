@@ -0,0 +1,216 @@
+// Package kv implements key-value store worker (redis, memcached).
+// It checks that server responds and compares INFO (stats) fields
+// with expected values and warn_at, fail_at thresholds.
+package kv
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernierasta/zorix/check/dialer"
+	"github.com/ernierasta/zorix/check/metrics"
+	"github.com/ernierasta/zorix/shared"
+)
+
+// KV worker
+type KV struct {
+	timeout shared.Duration
+}
+
+// New return new KV worker instance
+func New(timeout shared.Duration) *KV {
+	return &KV{timeout}
+}
+
+// Send checks redis or memcached server (by check type).
+// Returns returnCode, INFO (stats) response, time and error.
+func (k *KV) Send(c shared.CheckConfig) (int, string, int64, error) {
+	code, body, duration, _, err := k.SendVars(c)
+	return code, body, duration, err
+}
+
+// SendVars checks redis or memcached server (by check type).
+// Redis: AUTH (if auth_pass is set), PING and INFO (if any field is checked).
+// Memcached: stats.
+// Returns returnCode, INFO (stats) response, time, fields as vars and error.
+// For convince success returns code 200 and errors:
+//   - connection, auth or protocol error: 500
+//   - field not matching expect_info or over fail_at: 500
+//
+// Field over warn_at returns shared.Warning, which is processed as slowdown.
+func (k *KV) SendVars(c shared.CheckConfig) (int, string, int64, map[string]string, error) {
+	d, err := dialer.New(&c, k.timeout.Duration)
+	if err != nil {
+		return 500, "", 0, nil, fmt.Errorf("kv.Send: %v", err)
+	}
+	t0 := time.Now()
+	conn, err := d.DialContext(context.Background(), "tcp", c.Check)
+	if err != nil {
+		return 500, "", 0, nil, fmt.Errorf("kv.Send: can not connect to %s, err: %v", c.Check, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(t0.Add(k.timeout.Duration))
+
+	var info string
+	if c.Type == "memcached" {
+		info, err = memcachedStats(conn)
+	} else {
+		info, err = redisInfo(conn, c)
+	}
+	duration := time.Since(t0).Nanoseconds() / 1000 / 1000
+	if err != nil {
+		return 500, "", duration, nil, fmt.Errorf("kv.Send: %s %s, err: %v", c.Type, c.Check, err)
+	}
+
+	fields := parseInfo(info)
+	if err := checkFields(fields, c); err != nil {
+		if shared.IsWarning(err) {
+			return 200, info, duration, fields, shared.NewWarning("kv.Send: %v", err)
+		}
+		return 500, info, duration, fields, fmt.Errorf("kv.Send: %v", err)
+	}
+	return 200, info, duration, fields, nil
+}
+
+// checkFields compares fields with expect_info (failure if different)
+// and numeric fields with warn_at and fail_at thresholds.
+func checkFields(fields map[string]string, c shared.CheckConfig) error {
+	names := []string{}
+	for name := range c.ExpectInfo {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	wrong := []string{}
+	for _, name := range names {
+		if got, ok := fields[name]; !ok {
+			wrong = append(wrong, fmt.Sprintf("%s not found", name))
+		} else if got != c.ExpectInfo[name] {
+			wrong = append(wrong, fmt.Sprintf("%s is %q, expected %q", name, got, c.ExpectInfo[name]))
+		}
+	}
+	if len(wrong) > 0 {
+		return fmt.Errorf("%s", strings.Join(wrong, ", "))
+	}
+
+	v := metrics.Values{}
+	for name, val := range fields {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			v[name] = f
+		}
+	}
+	return metrics.Check(v, &c)
+}
+
+// redisInfo authenticates, sends PING and INFO if needed.
+func redisInfo(conn net.Conn, c shared.CheckConfig) (string, error) {
+	r := bufio.NewReader(conn)
+	if c.AuthPass != "" {
+		args := []string{"AUTH", c.AuthPass}
+		if c.AuthUser != "" {
+			args = []string{"AUTH", c.AuthUser, c.AuthPass} // redis 6 ACL
+		}
+		if _, err := redisCmd(conn, r, args...); err != nil {
+			return "", fmt.Errorf("auth failed: %v", err)
+		}
+	}
+	pong, err := redisCmd(conn, r, "PING")
+	if err != nil {
+		return "", err
+	}
+	if pong != "PONG" {
+		return "", fmt.Errorf("unexpected PING response: %q", pong)
+	}
+	if len(c.ExpectInfo) == 0 && len(c.WarnAt) == 0 && len(c.FailAt) == 0 &&
+		len(c.WarnBelow) == 0 && len(c.FailBelow) == 0 {
+		return pong, nil
+	}
+	return redisCmd(conn, r, "INFO")
+}
+
+// redisCmd sends command as RESP array and reads simple string,
+// integer or bulk string reply.
+func redisCmd(w io.Writer, r *bufio.Reader, args ...string) (string, error) {
+	cmd := fmt.Sprintf("*%d\r\n", len(args))
+	for _, a := range args {
+		cmd += fmt.Sprintf("$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(w, cmd); err != nil {
+		return "", err
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", fmt.Errorf("empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("%s", line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("wrong bulk reply: %q", line)
+		}
+		buf := make([]byte, n+2) // with \r\n
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	}
+	return "", fmt.Errorf("unexpected reply: %q", line)
+}
+
+// memcachedStats sends stats command and returns response without END.
+func memcachedStats(conn net.Conn) (string, error) {
+	if _, err := io.WriteString(conn, "stats\r\n"); err != nil {
+		return "", err
+	}
+	r := bufio.NewReader(conn)
+	lines := []string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "END":
+			return strings.Join(lines, "\n"), nil
+		case strings.HasPrefix(line, "STAT "):
+			lines = append(lines, line)
+		default:
+			return "", fmt.Errorf("unexpected stats response: %q", line)
+		}
+	}
+}
+
+// parseInfo parses redis INFO ("field:value") or memcached stats
+// ("STAT field value") to map.
+func parseInfo(info string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "STAT ") {
+			f := strings.SplitN(line[len("STAT "):], " ", 2)
+			if len(f) == 2 {
+				fields[f[0]] = f[1]
+			}
+			continue
+		}
+		if i := strings.Index(line, ":"); i > 0 && !strings.HasPrefix(line, "#") {
+			fields[line[:i]] = line[i+1:]
+		}
+	}
+	return fields
+}
@@ -0,0 +1,104 @@
+package kv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+const redisInfoReply = "# Server\r\nredis_version:7.0.0\r\n\r\n# Clients\r\nconnected_clients:12\r\n" +
+	"# Replication\r\nmaster_link_status:up\r\n# Persistence\r\nrdb_last_bgsave_status:ok\r\n"
+
+// serve starts fake server, every request line is answered by reply.
+func serve(t *testing.T, reply func(line string) string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					io.WriteString(conn, reply(strings.TrimSpace(line)))
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// fakeRedis answers RESP commands, only last line (command or argument) is used.
+func fakeRedis(line string) string {
+	switch line {
+	case "PING":
+		return "+PONG\r\n"
+	case "INFO":
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(redisInfoReply), redisInfoReply)
+	case "secret":
+		return "+OK\r\n"
+	case "wrong":
+		return "-WRONGPASS invalid password\r\n"
+	}
+	return ""
+}
+
+func fakeMemcached(line string) string {
+	if line == "stats" {
+		return "STAT pid 1\r\nSTAT curr_connections 10\r\nEND\r\n"
+	}
+	return "ERROR\r\n"
+}
+
+func TestKV_Send(t *testing.T) {
+	redis := serve(t, fakeRedis)
+	memcached := serve(t, fakeMemcached)
+	tests := []struct {
+		name        string
+		c           shared.CheckConfig
+		wantCode    int
+		wantWarning bool
+	}{
+		{"ping", shared.CheckConfig{Type: "redis", Check: redis}, 200, false},
+		{"auth", shared.CheckConfig{Type: "redis", Check: redis, AuthPass: "secret"}, 200, false},
+		{"wrong auth", shared.CheckConfig{Type: "redis", Check: redis, AuthPass: "wrong"}, 500, false},
+		{"expect info", shared.CheckConfig{Type: "redis", Check: redis,
+			ExpectInfo: map[string]string{"master_link_status": "up", "rdb_last_bgsave_status": "ok"}}, 200, false},
+		{"replication down", shared.CheckConfig{Type: "redis", Check: redis,
+			ExpectInfo: map[string]string{"master_link_status": "down"}}, 500, false},
+		{"clients warning", shared.CheckConfig{Type: "redis", Check: redis,
+			WarnAt: map[string]float64{"connected_clients": 10}}, 200, true},
+		{"memcached", shared.CheckConfig{Type: "memcached", Check: memcached,
+			FailAt: map[string]float64{"curr_connections": 100}}, 200, false},
+		{"memcached fail", shared.CheckConfig{Type: "memcached", Check: memcached,
+			FailAt: map[string]float64{"curr_connections": 5}}, 500, false},
+	}
+	k := New(shared.Duration{Duration: time.Second})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _, err := k.Send(tt.c)
+			if code != tt.wantCode {
+				t.Errorf("KV.Send() code = %d, want %d, err: %v", code, tt.wantCode, err)
+			}
+			if shared.IsWarning(err) != tt.wantWarning {
+				t.Errorf("KV.Send() err = %v, wantWarning %v", err, tt.wantWarning)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package mailloop
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+const (
+	// mailboxTimeout is timeout for one mailbox session.
+	mailboxTimeout = 30 * time.Second
+)
+
+// defaultPorts for mailbox url schemes.
+var defaultPorts = map[string]string{
+	"imap":  "143",
+	"imaps": "993",
+	"pop3":  "110",
+	"pop3s": "995",
+}
+
+// mailbox is IMAP or POP3 mailbox, where probes are delivered.
+type mailbox struct {
+	scheme, address, host string
+	user, pass, folder    string
+	ignoreCert            bool
+}
+
+// newMailbox parses mailbox url (imap://, imaps://, pop3://, pop3s://).
+func newMailbox(c shared.CheckConfig) (*mailbox, error) {
+	u, err := url.Parse(c.Mailbox)
+	if err != nil {
+		return nil, fmt.Errorf("wrong mailbox %q, err: %v", c.Mailbox, err)
+	}
+	port, ok := defaultPorts[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported mailbox scheme %q, use imap, imaps, pop3 or pop3s", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	folder := c.MailboxFolder
+	if folder == "" {
+		folder = "INBOX"
+	}
+	return &mailbox{
+		scheme:     u.Scheme,
+		address:    net.JoinHostPort(u.Hostname(), port),
+		host:       u.Hostname(),
+		user:       c.MailboxUser,
+		pass:       c.MailboxPass,
+		folder:     folder,
+		ignoreCert: c.IgnoreCert,
+	}, nil
+}
+
+// fetch opens mailbox session and looks for message with token.
+// Found probe is deleted together with probes of the same check older
+// than maxAge (not delivered in time), so they do not stay in mailbox.
+// Probes of other runs (f.e.: overlapping with this one) are kept.
+func (m *mailbox) fetch(token, prefix string, maxAge time.Duration) (bool, error) {
+	conn, err := net.DialTimeout("tcp", m.address, mailboxTimeout)
+	if err != nil {
+		return false, err
+	}
+	if m.scheme == "imaps" || m.scheme == "pop3s" {
+		conn = tls.Client(conn, &tls.Config{ServerName: m.host, InsecureSkipVerify: m.ignoreCert})
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(mailboxTimeout))
+
+	p := probes{token: token, prefix: prefix, before: time.Now().Add(-maxAge)}
+	tp := textproto.NewConn(conn)
+	if strings.HasPrefix(m.scheme, "imap") {
+		return m.imap(tp, p)
+	}
+	return m.pop3(tp, p)
+}
+
+// probes decides, which probes are deleted.
+type probes struct {
+	token, prefix string
+	// before is time, older probes are not delivered in time
+	before time.Time
+}
+
+// remove reports whether probe with header value v is deleted.
+// It is token itself or probe of the same check sent before p.before.
+func (p probes) remove(v string) bool {
+	if v == p.token {
+		return true
+	}
+	if !strings.HasPrefix(v, p.prefix) {
+		return false
+	}
+	n, err := strconv.ParseInt(v[len(p.prefix):], 10, 64)
+	return err == nil && time.Unix(0, n).Before(p.before)
+}
+
+// imap searches folder for probes of the check and fetches their
+// probe header (RFC 3501). Message numbers do not change until EXPUNGE.
+func (m *mailbox) imap(tp *textproto.Conn, p probes) (bool, error) {
+	if _, err := tp.ReadLine(); err != nil { // greeting
+		return false, err
+	}
+	if _, err := imapCmd(tp, "LOGIN %s %s", imapQuote(m.user), imapQuote(m.pass)); err != nil {
+		return false, err
+	}
+	if _, err := imapCmd(tp, "SELECT %s", imapQuote(m.folder)); err != nil {
+		return false, err
+	}
+	lines, err := imapCmd(tp, "SEARCH HEADER %s %s", probeHeader, imapQuote(p.prefix))
+	if err != nil {
+		return false, err
+	}
+	ids := imapSearchResult(lines)
+	if len(ids) == 0 {
+		imapCmd(tp, "LOGOUT")
+		return false, nil
+	}
+	lines, err = imapCmd(tp, "FETCH %s (BODY.PEEK[HEADER.FIELDS (%s)])", strings.Join(ids, ","), probeHeader)
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	remove := []string{}
+	for id, v := range imapFetchResult(lines) {
+		if v == p.token {
+			found = true
+		}
+		if p.remove(v) {
+			remove = append(remove, id)
+		}
+	}
+	if len(remove) > 0 {
+		if _, err := imapCmd(tp, "STORE %s +FLAGS.SILENT (\\Deleted)", strings.Join(remove, ",")); err != nil {
+			return found, err
+		}
+		if _, err := imapCmd(tp, "EXPUNGE"); err != nil {
+			return found, err
+		}
+	}
+	imapCmd(tp, "LOGOUT")
+	return found, nil
+}
+
+// imapCmd sends tagged command and returns untagged response lines.
+func imapCmd(tp *textproto.Conn, format string, args ...interface{}) ([]string, error) {
+	tag := fmt.Sprintf("z%d", tp.Next())
+	if err := tp.PrintfLine(tag+" "+format, args...); err != nil {
+		return nil, err
+	}
+	lines := []string{}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return lines, err
+		}
+		if !strings.HasPrefix(line, tag+" ") {
+			lines = append(lines, line)
+			continue
+		}
+		if !strings.HasPrefix(line[len(tag)+1:], "OK") {
+			cmd := strings.SplitN(format, " ", 2)[0]
+			return lines, fmt.Errorf("imap %s failed: %s", cmd, line[len(tag)+1:])
+		}
+		return lines, nil
+	}
+}
+
+// imapSearchResult returns message numbers from untagged SEARCH responses.
+func imapSearchResult(lines []string) []string {
+	ids := []string{}
+	for _, l := range lines {
+		if strings.HasPrefix(l, "* SEARCH") {
+			ids = append(ids, strings.Fields(l[len("* SEARCH"):])...)
+		}
+	}
+	return ids
+}
+
+// imapFetchResult returns probe header values of messages from
+// untagged FETCH responses, header is sent as literal on next lines.
+func imapFetchResult(lines []string) map[string]string {
+	headers := map[string][]string{}
+	id := ""
+	for _, l := range lines {
+		if f := strings.Fields(l); len(f) > 2 && f[0] == "*" && strings.EqualFold(f[2], "FETCH") {
+			id = f[1]
+			continue
+		}
+		if id != "" {
+			headers[id] = append(headers[id], l)
+		}
+	}
+	values := map[string]string{}
+	for id, h := range headers {
+		values[id] = probeValue(h)
+	}
+	return values
+}
+
+// imapQuote returns IMAP quoted string.
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// pop3 checks headers of all messages (RFC 1939). POP3 does not see
+// messages delivered during session, so every poll is new session.
+func (m *mailbox) pop3(tp *textproto.Conn, p probes) (bool, error) {
+	if _, err := pop3Cmd(tp, ""); err != nil { // greeting
+		return false, err
+	}
+	if _, err := pop3Cmd(tp, "USER %s", m.user); err != nil {
+		return false, err
+	}
+	if _, err := pop3Cmd(tp, "PASS %s", m.pass); err != nil {
+		return false, err
+	}
+	if _, err := pop3Cmd(tp, "LIST"); err != nil {
+		return false, err
+	}
+	list, err := tp.ReadDotLines()
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	remove := []string{}
+	for _, l := range list {
+		f := strings.Fields(l)
+		if len(f) == 0 {
+			continue
+		}
+		n := f[0]
+		if _, err := pop3Cmd(tp, "TOP %s 0", n); err != nil {
+			return false, err
+		}
+		header, err := tp.ReadDotLines()
+		if err != nil {
+			return false, err
+		}
+		v := probeValue(header)
+		if v == p.token {
+			found = true
+		}
+		if p.remove(v) {
+			remove = append(remove, n)
+		}
+	}
+
+	for _, n := range remove {
+		if _, err := pop3Cmd(tp, "DELE %s", n); err != nil {
+			return found, err
+		}
+	}
+	// deleted messages are removed on QUIT
+	_, err = pop3Cmd(tp, "QUIT")
+	return found, err
+}
+
+// pop3Cmd sends command (if not empty) and reads status line.
+func pop3Cmd(tp *textproto.Conn, format string, args ...interface{}) (string, error) {
+	if format != "" {
+		if err := tp.PrintfLine(format, args...); err != nil {
+			return "", err
+		}
+	}
+	line, err := tp.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		cmd := strings.SplitN(format, " ", 2)[0]
+		return line, fmt.Errorf("pop3 %s failed: %s", cmd, line)
+	}
+	return line, nil
+}
+
+// probeValue returns value of probe header from message header lines.
+func probeValue(header []string) string {
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(strings.Join(header, "\r\n") + "\r\n\r\n")))
+	h, _ := r.ReadMIMEHeader()
+	return strings.TrimSpace(h.Get(probeHeader))
+}
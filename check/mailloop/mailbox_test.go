@@ -0,0 +1,138 @@
+package mailloop
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	old    = time.Now().Add(-time.Hour).UnixNano()
+	recent = time.Now().UnixNano()
+	// fakeMessages are messages of fake servers, value is probe header:
+	// old probe of other check, old probe, probe of other run and token
+	fakeMessages = []string{
+		fmt.Sprintf("zorix:other:%d", old),
+		fmt.Sprintf("zorix:mail:%d", old),
+		fmt.Sprintf("zorix:mail:%d", recent-1),
+		fmt.Sprintf("zorix:mail:%d", recent),
+	}
+)
+
+// serve starts fake server, handle gets request lines and returns response.
+// deleted gets numbers of deleted messages.
+func serve(t *testing.T, greeting string, handle func(line string, deleted map[string]bool) string) (string, map[string]bool) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	deleted := map[string]bool{}
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, greeting)
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fmt.Fprint(conn, handle(strings.TrimSpace(line), deleted))
+		}
+	}()
+	return l.Addr().String(), deleted
+}
+
+func fakeIMAP(line string, deleted map[string]bool) string {
+	parts := strings.SplitN(line, " ", 2)
+	tag, cmd := parts[0], parts[1]
+	switch {
+	case strings.HasPrefix(cmd, "SEARCH HEADER"):
+		value := strings.Trim(strings.Fields(cmd)[3], `"`)
+		ids := []string{}
+		for i, m := range fakeMessages {
+			if strings.Contains(m, value) {
+				ids = append(ids, fmt.Sprint(i+1))
+			}
+		}
+		return fmt.Sprintf("* SEARCH %s\r\n%s OK done\r\n", strings.Join(ids, " "), tag)
+	case strings.HasPrefix(cmd, "FETCH"):
+		res := ""
+		for _, id := range strings.Split(strings.Fields(cmd)[1], ",") {
+			var n int
+			fmt.Sscan(id, &n)
+			header := fmt.Sprintf("%s: %s\r\n\r\n", probeHeader, fakeMessages[n-1])
+			res += fmt.Sprintf("* %s FETCH (BODY[HEADER.FIELDS (%s)] {%d}\r\n%s)\r\n", id, strings.ToUpper(probeHeader), len(header), header)
+		}
+		return res + tag + " OK done\r\n"
+	case strings.HasPrefix(cmd, "STORE"):
+		for _, id := range strings.Split(strings.Fields(cmd)[1], ",") {
+			deleted[id] = true
+		}
+	}
+	return tag + " OK done\r\n"
+}
+
+func fakePOP3(line string, deleted map[string]bool) string {
+	f := strings.Fields(line)
+	switch f[0] {
+	case "LIST":
+		list := "\r\n" // broken server, empty line is skipped
+		for i := range fakeMessages {
+			list += fmt.Sprintf("%d 100\r\n", i+1)
+		}
+		return "+OK\r\n" + list + ".\r\n"
+	case "TOP":
+		var n int
+		fmt.Sscan(f[1], &n)
+		return fmt.Sprintf("+OK\r\nSubject: test\r\n%s: %s\r\n.\r\n", probeHeader, fakeMessages[n-1])
+	case "DELE":
+		deleted[f[1]] = true
+	}
+	return "+OK\r\n"
+}
+
+func Test_mailbox_fetch(t *testing.T) {
+	tests := []struct {
+		name        string
+		scheme      string
+		token       string
+		wantFound   bool
+		wantDeleted map[string]bool
+	}{
+		{"imap found", "imap", fakeMessages[3], true, map[string]bool{"2": true, "4": true}},
+		{"imap not found, old deleted", "imap", fmt.Sprintf("zorix:mail:%d", recent+1), false, map[string]bool{"2": true}},
+		{"imap nothing to delete", "imap", "zorix:none:1", false, map[string]bool{}},
+		{"pop3 found", "pop3", fakeMessages[3], true, map[string]bool{"2": true, "4": true}},
+		{"pop3 not found, old deleted", "pop3", fmt.Sprintf("zorix:mail:%d", recent+1), false, map[string]bool{"2": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var addr string
+			var deleted map[string]bool
+			if tt.scheme == "imap" {
+				addr, deleted = serve(t, "* OK ready\r\n", fakeIMAP)
+			} else {
+				addr, deleted = serve(t, "+OK ready\r\n", fakePOP3)
+			}
+			m := &mailbox{scheme: tt.scheme, address: addr, user: "u", pass: "p", folder: "INBOX"}
+			found, err := m.fetch(tt.token, tt.token[:strings.LastIndex(tt.token, ":")+1], time.Minute)
+			if err != nil {
+				t.Fatalf("mailbox.fetch() error = %v", err)
+			}
+			if found != tt.wantFound {
+				t.Errorf("mailbox.fetch() = %v, want %v", found, tt.wantFound)
+			}
+			if fmt.Sprint(deleted) != fmt.Sprint(tt.wantDeleted) {
+				t.Errorf("mailbox.fetch() deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Package mailloop implements end-to-end mail delivery worker.
+// It sends uniquely tagged message via SMTP (the same code as mail
+// notification) and polls IMAP or POP3 mailbox until message arrives.
+package mailloop
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernierasta/zorix/notify/mail"
+	"github.com/ernierasta/zorix/shared"
+)
+
+const (
+	// probeHeader identifies probe messages, value is "zorix:ID:unique number".
+	probeHeader = "X-Zorix-Probe"
+)
+
+// Mailloop worker
+type Mailloop struct {
+	timeout shared.Duration
+}
+
+// New return new Mailloop worker instance
+func New(timeout shared.Duration) *Mailloop {
+	return &Mailloop{timeout}
+}
+
+// Send sends probe message and waits until it is delivered to mailbox.
+// Returns returnCode, empty body, delivery time and error.
+// For convince success returns code 200 and errors:
+//   - sending failed: 500
+//   - mailbox error or message not delivered within timeout: 500
+//
+// Delivered probe is deleted, also probes of the same check older than
+// timeout (not delivered in time). Probes of overlapping runs are kept.
+func (m *Mailloop) Send(c shared.CheckConfig) (int, string, int64, error) {
+	host, portStr, err := net.SplitHostPort(c.Check)
+	if err != nil {
+		return 500, "", 0, fmt.Errorf("mailloop.Send: wrong smtp address %q, err: %v", c.Check, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return 500, "", 0, fmt.Errorf("mailloop.Send: wrong smtp port %q, err: %v", portStr, err)
+	}
+	mb, err := newMailbox(c)
+	if err != nil {
+		return 500, "", 0, fmt.Errorf("mailloop.Send: %v", err)
+	}
+
+	prefix := fmt.Sprintf("zorix:%s:", c.ID)
+	token := fmt.Sprintf("%s%d", prefix, time.Now().UnixNano())
+	var auth smtp.Auth
+	if c.AuthUser != "" {
+		auth = smtp.PlainAuth("", c.AuthUser, c.AuthPass, host)
+	}
+
+	t0 := time.Now()
+	if err := mail.SendMail(host, port, auth, c.IgnoreCert, c.MailFrom, []string{c.MailTo}, message(c, token, t0)); err != nil {
+		return 500, "", 0, fmt.Errorf("mailloop.Send: can not send probe via %s, err: %v", c.Check, err)
+	}
+
+	deadline := t0.Add(m.timeout.Duration)
+	for {
+		found, err := mb.fetch(token, prefix, m.timeout.Duration)
+		duration := time.Since(t0).Nanoseconds() / 1000 / 1000
+		if err != nil {
+			return 500, "", duration, fmt.Errorf("mailloop.Send: mailbox %s error, err: %v", c.Mailbox, err)
+		}
+		if found {
+			return 200, "", duration, nil
+		}
+		if time.Now().Add(c.Interval.Duration).After(deadline) {
+			return 500, "", duration, fmt.Errorf("mailloop.Send: probe not delivered to %s within %s", c.Mailbox, m.timeout.Duration)
+		}
+		time.Sleep(c.Interval.Duration)
+	}
+}
+
+// message returns probe message.
+func message(c shared.CheckConfig, token string, t time.Time) []byte {
+	hostname, _ := os.Hostname()
+	header := []string{
+		"From: " + c.MailFrom,
+		"To: " + c.MailTo,
+		"Date: " + t.Format(time.RFC1123Z),
+		fmt.Sprintf("Message-ID: <%d.zorix@%s>", t.UnixNano(), hostname),
+		"Subject: zorix mail loop probe " + c.ID,
+		probeHeader + ": " + token,
+		"Content-Type: text/plain; charset=\"utf-8\"",
+	}
+	body := "This message is sent by zorix monitoring to test mail delivery, it will be deleted automatically."
+	return []byte(strings.Join(header, "\r\n") + "\r\n\r\n" + body + "\r\n")
+}
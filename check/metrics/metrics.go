@@ -0,0 +1,96 @@
+// Package metrics contains helpers for resource workers (ssh, system).
+// It parses /proc data and evaluates measured values against
+// warn and fail thresholds (upper and lower bounds).
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+// Values are measured metrics, f.e.: "load1": 0.5, "mem_used": 42.
+type Values map[string]float64
+
+// Check compares values with check thresholds, value over fail_at
+// or under fail_below is failure, over warn_at or under warn_below
+// is warning (shared.Warning).
+// Missing value for configured threshold is failure.
+// Returns nil if all values are ok.
+func Check(v Values, c *shared.CheckConfig) error {
+	fails := append(exceeded(v, c.FailAt, false), exceeded(v, c.FailBelow, true)...)
+	if len(fails) > 0 {
+		return fmt.Errorf("%s", strings.Join(fails, ", "))
+	}
+	warns := append(exceeded(v, c.WarnAt, false), exceeded(v, c.WarnBelow, true)...)
+	if len(warns) > 0 {
+		return shared.NewWarning("%s", strings.Join(warns, ", "))
+	}
+	return nil
+}
+
+// exceeded returns descriptions of values over thresholds (under
+// thresholds if below is true), sorted by name.
+func exceeded(v Values, thresholds map[string]float64, below bool) []string {
+	res := []string{}
+	for _, name := range sortedKeys(thresholds) {
+		val, ok := v[name]
+		switch {
+		case !ok:
+			res = append(res, fmt.Sprintf("%s not measured", name))
+		case !below && val > thresholds[name]:
+			res = append(res, fmt.Sprintf("%s %s > %s", name, format(val), format(thresholds[name])))
+		case below && val < thresholds[name]:
+			res = append(res, fmt.Sprintf("%s %s < %s", name, format(val), format(thresholds[name])))
+		}
+	}
+	return res
+}
+
+// Vars returns values formatted as template variables.
+func (v Values) Vars() map[string]string {
+	vars := map[string]string{}
+	for k, val := range v {
+		vars[k] = format(val)
+	}
+	return vars
+}
+
+// String returns values as "name: value" lines, sorted by name.
+func (v Values) String() string {
+	lines := []string{}
+	for _, k := range sortedKeys(v) {
+		lines = append(lines, k+": "+format(v[k]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// format returns shortest representation of value.
+func format(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// percent returns part/total in percent rounded to 2 decimal places, 0 if total is 0.
+func percent(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return round(part / total * 100)
+}
+
+// round rounds to 2 decimal places.
+func round(f float64) float64 {
+	return float64(int64(f*100+0.5)) / 100
+}
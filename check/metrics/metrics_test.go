@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+func TestCheck(t *testing.T) {
+	v := Values{"load1": 2.5, "mem_used": 91, "uptime": 300}
+	tests := []struct {
+		name        string
+		c           shared.CheckConfig
+		wantErr     string
+		wantWarning bool
+	}{
+		{"no thresholds", shared.CheckConfig{}, "", false},
+		{"ok", shared.CheckConfig{WarnAt: map[string]float64{"load1": 4}, FailAt: map[string]float64{"mem_used": 95}}, "", false},
+		{"warning", shared.CheckConfig{WarnAt: map[string]float64{"load1": 2}, FailAt: map[string]float64{"mem_used": 95}}, "load1 2.5 > 2", true},
+		{"failure", shared.CheckConfig{WarnAt: map[string]float64{"load1": 2}, FailAt: map[string]float64{"mem_used": 90}}, "mem_used 91 > 90", false},
+		{"not measured", shared.CheckConfig{FailAt: map[string]float64{"swap_used": 50}}, "swap_used not measured", false},
+		{"below ok", shared.CheckConfig{WarnBelow: map[string]float64{"uptime": 300}, FailBelow: map[string]float64{"load1": 1}}, "", false},
+		{"warning below", shared.CheckConfig{WarnBelow: map[string]float64{"uptime": 600}}, "uptime 300 < 600", true},
+		{"failure below", shared.CheckConfig{WarnAt: map[string]float64{"load1": 2}, FailBelow: map[string]float64{"uptime": 600}}, "uptime 300 < 600", false},
+		{"both bounds", shared.CheckConfig{FailAt: map[string]float64{"mem_used": 95}, FailBelow: map[string]float64{"mem_used": 92}}, "mem_used 91 < 92", false},
+		{"not measured below", shared.CheckConfig{WarnBelow: map[string]float64{"swap_used": 1}}, "swap_used not measured", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(v, &tt.c)
+			if (err != nil) != (tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+			if shared.IsWarning(err) != tt.wantWarning {
+				t.Errorf("Check() error = %v, wantWarning %v", err, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestParseMeminfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		meminfo string
+		want    Values
+	}{
+		{"available", "MemTotal: 1000 kB\nMemFree: 100 kB\nMemAvailable: 250 kB\nSwapTotal: 200 kB\nSwapFree: 150 kB\n",
+			Values{"mem_used": 75, "swap_used": 25}},
+		{"old kernel, no swap", "MemTotal: 1000 kB\nMemFree: 100 kB\nBuffers: 50 kB\nCached: 150 kB\nSwapTotal: 0 kB\nSwapFree: 0 kB\n",
+			Values{"mem_used": 70, "swap_used": 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Values{}
+			if err := ParseMeminfo(tt.meminfo, got); err != nil {
+				t.Fatalf("ParseMeminfo() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMeminfo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDf(t *testing.T) {
+	df := `Filesystem     1024-blocks      Used Available Capacity Mounted on
+/dev/sda1         10000      4000      6000      40% /
+tmpfs              1000       900       100      90% /run
+/dev/sdb1         10000      7000      3000      70% /var/lib/my data
+`
+	tests := []struct {
+		name   string
+		mounts []string
+		want   Values
+	}{
+		{"devices", nil, Values{"disk_used": 70, "disk_used:/": 40, "disk_used:/var/lib/my data": 70}},
+		{"selected", []string{"/", "/run"}, Values{"disk_used": 90, "disk_used:/": 40, "disk_used:/run": 90}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Values{}
+			if err := ParseDf(df, "disk_used", tt.mounts, got); err != nil {
+				t.Fatalf("ParseDf() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNetDev(t *testing.T) {
+	netdev := `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  500 5 0 0 0 0 0 0 500 5 0 0 0 0 0 0
+  eth0: 1000 10 0 0 0 0 0 0 2000 20 0 0 0 0 0 0
+  eth1:  100 1 0 0 0 0 0 0 200 2 0 0 0 0 0 0
+`
+	got := Values{}
+	if err := ParseNetDev(netdev, got); err != nil {
+		t.Fatalf("ParseNetDev() error = %v", err)
+	}
+	want := Values{"rx_bytes": 1100, "tx_bytes": 2200}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseNetDev() = %v, want %v", got, want)
+	}
+}
+
+func TestParseFileNr(t *testing.T) {
+	got := Values{}
+	if err := ParseFileNr("2048\t0\t8192\n", got); err != nil {
+		t.Fatalf("ParseFileNr() error = %v", err)
+	}
+	want := Values{"open_fds": 2048, "fds_used": 25}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseFileNr() = %v, want %v", got, want)
+	}
+}
+
+func TestParseMounts(t *testing.T) {
+	mounts := "/dev/sda1 / ext4 rw 0 0\nproc /proc proc rw 0 0\n/dev/sdb1 /mnt/my\\040disk ext4 rw 0 0\n/dev/sda1 / ext4 rw 0 0\n"
+	want := []string{"/", "/mnt/my disk"}
+	if got := ParseMounts(mounts); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMounts() = %v, want %v", got, want)
+	}
+}
+
+func TestAddUsage(t *testing.T) {
+	got := Values{}
+	AddUsage(got, "disk_used", "/", 50, 200)
+	AddUsage(got, "disk_used", "/var", 90, 100)
+	AddUsage(got, "inode_used", "/btrfs", 0, 0)
+	want := Values{"disk_used": 90, "disk_used:/": 25, "disk_used:/var": 90}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AddUsage() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,220 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseLoadavg parses /proc/loadavg, sets load1, load5, load15.
+func ParseLoadavg(s string, v Values) error {
+	f := strings.Fields(s)
+	if len(f) < 3 {
+		return fmt.Errorf("wrong loadavg format: %q", s)
+	}
+	for i, name := range []string{"load1", "load5", "load15"} {
+		l, err := strconv.ParseFloat(f[i], 64)
+		if err != nil {
+			return fmt.Errorf("wrong loadavg format: %q", s)
+		}
+		v[name] = l
+	}
+	return nil
+}
+
+// ParseMeminfo parses /proc/meminfo, sets mem_used and swap_used in percent.
+// Used memory is MemTotal - MemAvailable (MemFree + Buffers + Cached on old kernels).
+func ParseMeminfo(s string, v Values) error {
+	m := map[string]float64{}
+	for _, line := range strings.Split(s, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		val, err := strconv.ParseFloat(f[1], 64)
+		if err != nil {
+			continue
+		}
+		m[strings.TrimSuffix(f[0], ":")] = val
+	}
+	total, ok := m["MemTotal"]
+	if !ok {
+		return fmt.Errorf("wrong meminfo format, MemTotal not found")
+	}
+	avail, ok := m["MemAvailable"]
+	if !ok {
+		avail = m["MemFree"] + m["Buffers"] + m["Cached"]
+	}
+	v["mem_used"] = percent(total-avail, total)
+	v["swap_used"] = percent(m["SwapTotal"]-m["SwapFree"], m["SwapTotal"])
+	return nil
+}
+
+// ParseUptime parses /proc/uptime, sets uptime in seconds.
+func ParseUptime(s string, v Values) error {
+	f := strings.Fields(s)
+	if len(f) < 1 {
+		return fmt.Errorf("wrong uptime format: %q", s)
+	}
+	u, err := strconv.ParseFloat(f[0], 64)
+	if err != nil {
+		return fmt.Errorf("wrong uptime format: %q", s)
+	}
+	v["uptime"] = u
+	return nil
+}
+
+// ParseNetDev parses /proc/net/dev, sets rx_bytes and tx_bytes
+// summed for all interfaces except loopback.
+func ParseNetDev(s string, v Values) error {
+	var rx, tx float64
+	for _, line := range strings.Split(s, "\n") {
+		i := strings.Index(line, ":")
+		if i == -1 {
+			continue // header
+		}
+		iface := strings.TrimSpace(line[:i])
+		f := strings.Fields(line[i+1:])
+		if iface == "lo" || len(f) < 9 {
+			continue
+		}
+		r, err1 := strconv.ParseFloat(f[0], 64)
+		t, err2 := strconv.ParseFloat(f[8], 64)
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("wrong net/dev format: %q", line)
+		}
+		rx += r
+		tx += t
+	}
+	v["rx_bytes"] = rx
+	v["tx_bytes"] = tx
+	return nil
+}
+
+// ParseDf parses `df -P` (or `df -Pi`) output and sets prefix for every
+// mount (f.e. "disk_used:/var") and prefix with maximum of all mounts.
+// If mounts is empty, all mounts of real devices (starting with "/") are used.
+func ParseDf(s, prefix string, mounts []string, v Values) error {
+	max := -1.0
+	for _, line := range strings.Split(s, "\n")[1:] {
+		f := strings.Fields(line)
+		if len(f) < 6 {
+			continue
+		}
+		dev, mount := f[0], strings.Join(f[5:], " ")
+		if !selected(dev, mount, mounts) {
+			continue
+		}
+		used, err := strconv.ParseFloat(strings.TrimSuffix(f[4], "%"), 64)
+		if err != nil {
+			continue // f.e.: "-" for inodes on some filesystems
+		}
+		v[prefix+":"+mount] = used
+		if used > max {
+			max = used
+		}
+	}
+	if max < 0 {
+		return fmt.Errorf("no filesystem found for %s", prefix)
+	}
+	v[prefix] = max
+	return nil
+}
+
+// selected returns true if mount should be measured.
+func selected(dev, mount string, mounts []string) bool {
+	if len(mounts) == 0 {
+		return strings.HasPrefix(dev, "/")
+	}
+	for _, m := range mounts {
+		if m == mount {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadPerCore sets load1_core, load5_core and load15_core,
+// load averages divided by number of cores.
+func LoadPerCore(v Values, cores int) {
+	if cores < 1 {
+		cores = 1
+	}
+	for _, name := range []string{"load1", "load5", "load15"} {
+		if l, ok := v[name]; ok {
+			v[name+"_core"] = round(l / float64(cores))
+		}
+	}
+}
+
+// ParseFileNr parses /proc/sys/fs/file-nr, sets open_fds
+// and fds_used in percent of system limit.
+func ParseFileNr(s string, v Values) error {
+	f := strings.Fields(s)
+	if len(f) < 3 {
+		return fmt.Errorf("wrong file-nr format: %q", s)
+	}
+	nr := make([]float64, 3)
+	for i := range nr {
+		n, err := strconv.ParseFloat(f[i], 64)
+		if err != nil {
+			return fmt.Errorf("wrong file-nr format: %q", s)
+		}
+		nr[i] = n
+	}
+	v["open_fds"] = nr[0] - nr[1] // allocated - unused
+	v["fds_used"] = percent(v["open_fds"], nr[2])
+	return nil
+}
+
+// ParseMounts parses /proc/mounts and returns mount points of real
+// devices (starting with "/"), the same as used by ParseDf.
+func ParseMounts(s string) []string {
+	mounts := []string{}
+	seen := map[string]bool{}
+	for _, line := range strings.Split(s, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 2 || !strings.HasPrefix(f[0], "/") {
+			continue
+		}
+		mount := unescape(f[1])
+		if !seen[mount] {
+			seen[mount] = true
+			mounts = append(mounts, mount)
+		}
+	}
+	return mounts
+}
+
+// AddUsage sets prefix for mount (f.e. "disk_used:/var") in percent
+// and updates prefix with maximum of all mounts. Zero total is ignored
+// (f.e.: filesystems without inodes).
+func AddUsage(v Values, prefix, mount string, used, total float64) {
+	if total <= 0 {
+		return
+	}
+	p := percent(used, total)
+	v[prefix+":"+mount] = p
+	if max, ok := v[prefix]; !ok || p > max {
+		v[prefix] = p
+	}
+}
+
+// unescape decodes octal escapes in /proc/mounts (f.e.: "\040" is space).
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	b := []byte{}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b = append(b, byte(n))
+				i += 3
+				continue
+			}
+		}
+		b = append(b, s[i])
+	}
+	return string(b)
+}
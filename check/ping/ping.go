@@ -1,42 +1,281 @@
-// Package ping implements external ping worker.
-// We are using system ping, becouse on every platform,
-// there is some form of setuid on this binary, so ordinary user can run it.
+// Package ping implements native ICMP echo worker.
+// It uses unprivileged datagram ICMP sockets (on Linux allowed by
+// net.ipv4.ping_group_range sysctl) and falls back to raw sockets,
+// which require root or CAP_NET_RAW.
 package ping
 
 import (
+	"bytes"
 	"fmt"
-	"os/exec"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/ernierasta/zorix/shared"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
 const (
-	timeout = 10 * time.Minute
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
 )
 
 // Ping worker
 type Ping struct {
 	timeout shared.Duration
+	id      int
+	seq     uint32
 }
 
 // New return new Ping worker instance
 func New(timeout shared.Duration) *Ping {
-	return &Ping{timeout}
+	return &Ping{timeout: timeout, id: (os.Getpid() ^ rand.Intn(0xffff)) & 0xffff}
 }
 
-// Send runs ping command with reasonable.
-// Returns returnCode, ping output, requestTime and error.
-// For convince success returns code 200 and errors:
-//   - non zero ping status: 500
+// Send sends c.Count ICMP echo requests to c.Check and waits for replies.
+// Returns returnCode, statistics, average round trip time and error.
 func (w *Ping) Send(c shared.CheckConfig) (int, string, int64, error) {
-	t0 := time.Now()
-	ping := exec.Command("ping", c.Check, "-c1", fmt.Sprintf("-t%.0f", w.timeout.Seconds()))
-	out, err := ping.Output()
-	duration := time.Since(t0)
+	code, out, avg, _, err := w.SendVars(c)
+	return code, out, avg, err
+}
+
+// SendVars sends c.Count ICMP echo requests to c.Check, c.Interval apart
+// and computes min/avg/max round trip time, jitter and packet loss.
+// Whole run is bounded by ping timeout, probes left share remaining time,
+// so lost reply does not delay following probes. Probes, which can not
+// be sent before timeout, are counted as unsent (not lost).
+// Returns returnCode, statistics, average round trip time, statistics vars and error.
+// For convince success returns code 200 and errors:
+//   - host not resolved, packet loss above c.MaxLoss: 500
+//
+// Unsent probes return shared.Warning, which is processed as slowdown.
+func (w *Ping) SendVars(c shared.CheckConfig) (int, string, int64, map[string]string, error) {
+	ip, err := resolve(c.Check)
+	if err != nil {
+		return 500, "", 0, nil, fmt.Errorf("ping.Send: can not resolve %s, err: %v", c.Check, err)
+	}
+
+	conn, privileged, err := listen(ip)
 	if err != nil {
-		return 500, "", 0, fmt.Errorf("ping.Send: ping returned non zero status, err: %v", err)
+		return 500, "", 0, nil, fmt.Errorf("ping.Send: can not open icmp socket, err: %v", err)
+	}
+	defer conn.Close()
+
+	count := c.Count
+	if count < 1 {
+		count = 1
+	}
+	deadline := time.Now().Add(w.timeout.Duration)
+	rtts := []time.Duration{}
+	sent := 0
+	var lastErr error
+	for ; sent < count; sent++ {
+		if sent > 0 {
+			if time.Now().Add(c.Interval.Duration).After(deadline) {
+				break
+			}
+			time.Sleep(c.Interval.Duration)
+		}
+		seq := int(atomic.AddUint32(&w.seq, 1) & 0xffff)
+		wait := time.Until(deadline) / time.Duration(count-sent)
+		rtt, err := w.probe(conn, ip, privileged, seq, time.Now().Add(wait))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		rtts = append(rtts, rtt)
+	}
+
+	st := statistics(rtts, sent)
+	st.unsent = count - sent
+	vars := st.vars()
+	out := fmt.Sprintf("%s (%s): %s", c.Check, ip, st)
+	if len(rtts) == 0 {
+		return 500, out, 0, vars, fmt.Errorf("ping.Send: no reply from %s (%s), err: %v", c.Check, ip, lastErr)
+	}
+	if st.loss > float64(c.MaxLoss) {
+		return 500, out, ms(st.avg), vars, fmt.Errorf("ping.Send: packet loss %.1f%% for %s (%s) exceeds %d%%", st.loss, c.Check, ip, c.MaxLoss)
+	}
+	if st.unsent > 0 {
+		return 200, out, ms(st.avg), vars, shared.NewWarning("ping.Send: ping timeout %s exceeded, %d of %d requests sent to %s (%s)",
+			w.timeout.Duration, sent, count, c.Check, ip)
 	}
-	return 200, string(out), duration.Nanoseconds() / 1000 / 1000, nil
+	return 200, out, ms(st.avg), vars, nil
+}
+
+// stats represents ping statistics for one run.
+// Loss is computed from sent requests, unsent are not counted.
+type stats struct {
+	sent, received     int
+	unsent             int
+	loss               float64 // percent
+	min, avg, max, jit time.Duration
+}
+
+// statistics computes stats from received round trip times.
+// Jitter is mean absolute difference of consecutive round trip times.
+func statistics(rtts []time.Duration, sent int) stats {
+	st := stats{sent: sent, received: len(rtts)}
+	if sent > 0 {
+		st.loss = float64(sent-len(rtts)) * 100 / float64(sent)
+	}
+	if len(rtts) == 0 {
+		return st
+	}
+	var sum, jsum time.Duration
+	st.min, st.max = rtts[0], rtts[0]
+	for i, rtt := range rtts {
+		sum += rtt
+		if rtt < st.min {
+			st.min = rtt
+		}
+		if rtt > st.max {
+			st.max = rtt
+		}
+		if i > 0 {
+			d := rtt - rtts[i-1]
+			if d < 0 {
+				d = -d
+			}
+			jsum += d
+		}
+	}
+	st.avg = sum / time.Duration(len(rtts))
+	if len(rtts) > 1 {
+		st.jit = jsum / time.Duration(len(rtts)-1)
+	}
+	return st
+}
+
+// String returns ping like summary.
+func (st stats) String() string {
+	unsent := ""
+	if st.unsent > 0 {
+		unsent = fmt.Sprintf(", %d not sent (timeout)", st.unsent)
+	}
+	return fmt.Sprintf("%d packets transmitted, %d received, %.1f%% packet loss%s, rtt min/avg/max/jitter = %s/%s/%s/%s ms",
+		st.sent, st.received, st.loss, unsent, fms(st.min), fms(st.avg), fms(st.max), fms(st.jit))
+}
+
+// vars returns stats as template variables.
+func (st stats) vars() map[string]string {
+	return map[string]string{
+		"sent":     strconv.Itoa(st.sent),
+		"received": strconv.Itoa(st.received),
+		"unsent":   strconv.Itoa(st.unsent),
+		"loss":     strconv.FormatFloat(st.loss, 'f', 1, 64),
+		"rtt_min":  fms(st.min),
+		"rtt_avg":  fms(st.avg),
+		"rtt_max":  fms(st.max),
+		"jitter":   fms(st.jit),
+	}
+}
 
+// ms returns duration in whole milliseconds.
+func ms(d time.Duration) int64 {
+	return d.Nanoseconds() / 1000 / 1000
+}
+
+// fms returns duration in milliseconds formatted with 3 decimals.
+func fms(d time.Duration) string {
+	return strconv.FormatFloat(float64(d.Nanoseconds())/1000/1000, 'f', 3, 64)
+}
+
+// probe sends one echo request and waits for matching reply until deadline.
+// Returns round trip time.
+func (w *Ping) probe(conn *icmp.PacketConn, ip net.IP, privileged bool, seq int, deadline time.Time) (time.Duration, error) {
+	var typ icmp.Type = ipv4.ICMPTypeEcho
+	proto := protocolICMP
+	if ip.To4() == nil {
+		typ = ipv6.ICMPTypeEchoRequest
+		proto = protocolIPv6ICMP
+	}
+
+	payload := []byte(fmt.Sprintf("zorix-%d-%d", w.id, seq))
+	msg := icmp.Message{
+		Type: typ,
+		Body: &icmp.Echo{ID: w.id, Seq: seq, Data: payload},
+	}
+	wb, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	if privileged {
+		dst = &net.IPAddr{IP: ip}
+	}
+
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return 0, err
+	}
+	t0 := time.Now()
+	if _, err := conn.WriteTo(wb, dst); err != nil {
+		return 0, err
+	}
+
+	rb := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(rb)
+		if err != nil {
+			return 0, err
+		}
+		rtt := time.Since(t0)
+		reply, err := icmp.ParseMessage(proto, rb[:n])
+		if err != nil {
+			continue
+		}
+		echo, ok := reply.Body.(*icmp.Echo)
+		if !ok || (reply.Type != ipv4.ICMPTypeEchoReply && reply.Type != ipv6.ICMPTypeEchoReply) {
+			continue
+		}
+		// kernel rewrites ID for datagram sockets, so match by seq and payload
+		if echo.Seq != seq || !bytes.Equal(echo.Data, payload) {
+			continue
+		}
+		return rtt, nil
+	}
+}
+
+// listen opens unprivileged datagram ICMP socket, if not permitted
+// it tries raw socket. Returns true if raw socket is used.
+func listen(ip net.IP) (*icmp.PacketConn, bool, error) {
+	network, rawNetwork, address := "udp4", "ip4:icmp", "0.0.0.0"
+	if ip.To4() == nil {
+		network, rawNetwork, address = "udp6", "ip6:ipv6-icmp", "::"
+	}
+	conn, err := icmp.ListenPacket(network, address)
+	if err == nil {
+		return conn, false, nil
+	}
+	conn, rawErr := icmp.ListenPacket(rawNetwork, address)
+	if rawErr != nil {
+		return nil, false, fmt.Errorf("datagram socket: %v, raw socket: %v", err, rawErr)
+	}
+	return conn, true, nil
+}
+
+// resolve returns IP address of host, IPv4 is preferred.
+func resolve(host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no address found")
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip, nil
+		}
+	}
+	return ips[0], nil
 }
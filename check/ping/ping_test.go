@@ -0,0 +1,103 @@
+package ping
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+	"golang.org/x/net/icmp"
+)
+
+func Test_statistics(t *testing.T) {
+	ms := time.Millisecond
+	type args struct {
+		rtts []time.Duration
+		sent int
+	}
+	tests := []struct {
+		name string
+		args args
+		want stats
+	}{
+		{"all lost", args{[]time.Duration{}, 3}, stats{sent: 3, received: 0, loss: 100}},
+		{"one reply", args{[]time.Duration{10 * ms}, 1}, stats{sent: 1, received: 1, min: 10 * ms, avg: 10 * ms, max: 10 * ms}},
+		{"jitter", args{[]time.Duration{10 * ms, 20 * ms, 12 * ms}, 3}, stats{sent: 3, received: 3, min: 10 * ms, avg: 14 * ms, max: 20 * ms, jit: 9 * ms}},
+		{"loss", args{[]time.Duration{10 * ms, 30 * ms}, 4}, stats{sent: 4, received: 2, loss: 50, min: 10 * ms, avg: 20 * ms, max: 30 * ms, jit: 20 * ms}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statistics(tt.args.rtts, tt.args.sent); got != tt.want {
+				t.Errorf("statistics() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// loopback returns connection for 127.0.0.1, test is skipped
+// if neither datagram nor raw ICMP socket is allowed.
+func loopback(t *testing.T) (*icmp.PacketConn, bool) {
+	conn, privileged, err := listen(net.IPv4(127, 0, 0, 1))
+	if err != nil {
+		t.Skipf("icmp not permitted (see net.ipv4.ping_group_range), err: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, privileged
+}
+
+func TestPing_probe(t *testing.T) {
+	conn, privileged := loopback(t)
+	w := New(shared.Duration{Duration: time.Second})
+	for seq := 1; seq <= 3; seq++ {
+		rtt, err := w.probe(conn, net.IPv4(127, 0, 0, 1), privileged, seq, time.Now().Add(time.Second))
+		if err != nil {
+			t.Fatalf("Ping.probe() error = %v", err)
+		}
+		if rtt <= 0 || rtt > time.Second {
+			t.Errorf("Ping.probe() rtt = %s, want (0, 1s]", rtt)
+		}
+	}
+}
+
+func TestPing_SendVars(t *testing.T) {
+	loopback(t)
+	w := New(shared.Duration{Duration: time.Second})
+	c := shared.CheckConfig{Check: "127.0.0.1", Count: 3, Interval: shared.Duration{Duration: 10 * time.Millisecond}}
+	code, _, _, vars, err := w.SendVars(c)
+	if err != nil || code != 200 {
+		t.Fatalf("Ping.SendVars() = %d, %v, want 200", code, err)
+	}
+	if vars["received"] != "3" || vars["loss"] != "0.0" {
+		t.Errorf("Ping.SendVars() vars = %v, want 3 received", vars)
+	}
+}
+
+func TestPing_SendVars_deadline(t *testing.T) {
+	loopback(t)
+	timeout := 300 * time.Millisecond
+	w := New(shared.Duration{Duration: timeout})
+	// all requests with intervals would take 900 ms
+	c := shared.CheckConfig{Check: "127.0.0.1", Count: 10, Interval: shared.Duration{Duration: 100 * time.Millisecond}}
+	t0 := time.Now()
+	code, _, _, vars, err := w.SendVars(c)
+	if d := time.Since(t0); d > timeout {
+		t.Errorf("Ping.SendVars() took %s, want whole run bounded by %s", d, timeout)
+	}
+	if !shared.IsWarning(err) || code != 200 {
+		t.Errorf("Ping.SendVars() = %d, %v, want 200 and warning", code, err)
+	}
+	sent, _ := strconv.Atoi(vars["sent"])
+	unsent, _ := strconv.Atoi(vars["unsent"])
+	if sent == 0 || unsent == 0 || sent+unsent != 10 || vars["loss"] != "0.0" {
+		t.Errorf("Ping.SendVars() vars = %v, want only part of 10 requests sent, unsent not lost", vars)
+	}
+}
+
+func TestPing_probe_deadline(t *testing.T) {
+	conn, privileged := loopback(t)
+	w := New(shared.Duration{Duration: time.Second})
+	if _, err := w.probe(conn, net.IPv4(127, 0, 0, 1), privileged, 1, time.Now().Add(-time.Millisecond)); err == nil {
+		t.Errorf("Ping.probe() with passed deadline, want error")
+	}
+}
@@ -1,14 +1,26 @@
 // Package port implements portscanner worker.
+// Optionally it can send payload and expect response, which allows
+// checking simple line protocols (redis, smtp banner, memcached, ...).
 package port
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/ernierasta/zorix/check/dialer"
 	"github.com/ernierasta/zorix/shared"
 )
 
+const (
+	// maxResponse is max amount of bytes read from service.
+	maxResponse = 64 * 1024
+)
+
 // Port worker
 type Port struct {
 	timeout shared.Duration
@@ -20,17 +32,97 @@ func New(timeout shared.Duration) *Port {
 }
 
 // Send runs portscan.
-// Returns returnCode, empty body, requestTime and error.
+// Returns returnCode, response, requestTime and error.
 // For convince success returns code 200 and errors:
 //   - closed: 500
+//   - unexpected or missing response: 500
+//
+// If 'send' is set, payload is written after connect. If 'expect'
+// or 'expect_regex' is set, response is read until it matches (or timeout).
+// UDP protocol always sends payload and waits for reply.
+// Whole exchange has to fit into port timeout.
 func (p *Port) Send(c shared.CheckConfig) (int, string, int64, error) {
+	d, err := dialer.New(&c, p.timeout.Duration)
+	if err != nil {
+		return 500, "", 0, fmt.Errorf("port.Send: %v", err)
+	}
 	t0 := time.Now()
-	conn, err := net.DialTimeout("tcp", c.Check, p.timeout.Duration)
-	duration := time.Since(t0)
+	var conn net.Conn
+	if c.Protocol == "udp" {
+		// proxies do not support udp, dial directly
+		conn, err = d.DialContext(context.Background(), "udp", c.Check)
+	} else {
+		conn, err = d.Dial("tcp", c.Check)
+	}
 	if err != nil {
 		return 500, "", 0, fmt.Errorf("port.Send: port returned non zero status, err: %v", err)
 	}
-	conn.Close()
-	return 200, "", duration.Nanoseconds() / 1000 / 1000, nil
+	defer conn.Close()
+
+	if c.Payload == "" && c.Expect == "" && c.ExpectRegex == "" {
+		return 200, "", ms(time.Since(t0)), nil
+	}
+
+	conn.SetDeadline(t0.Add(p.timeout.Duration))
+	if c.Payload != "" {
+		if _, err := conn.Write([]byte(c.Payload)); err != nil {
+			return 500, "", ms(time.Since(t0)), fmt.Errorf("port.Send: can not send payload, err: %v", err)
+		}
+	}
+	resp, err := read(conn, c.Protocol == "udp", matcher(c))
+	duration := time.Since(t0)
+	if err != nil {
+		return 500, resp, ms(duration), fmt.Errorf("port.Send: %v", err)
+	}
+	return 200, resp, ms(duration), nil
+}
+
+// matcher returns function which reports if response is complete.
+// Without expectations any response is enough.
+func matcher(c shared.CheckConfig) func(string) bool {
+	var re *regexp.Regexp
+	if c.ExpectRegex != "" {
+		re = regexp.MustCompile(c.ExpectRegex) // validated in config
+	}
+	return func(resp string) bool {
+		if c.Expect != "" && !strings.Contains(resp, c.Expect) {
+			return false
+		}
+		if re != nil && !re.MatchString(resp) {
+			return false
+		}
+		return true
+	}
+}
+
+// read reads from connection until matched returns true. UDP reply is
+// only one datagram, so it is read once.
+func read(conn net.Conn, udp bool, matched func(string) bool) (string, error) {
+	buf := make([]byte, maxResponse)
+	resp := ""
+	for {
+		n, err := conn.Read(buf)
+		resp += string(buf[:n])
+		if len(resp) > maxResponse {
+			resp = resp[:maxResponse]
+		}
+		if n > 0 && matched(resp) {
+			return resp, nil
+		}
+		switch {
+		case err == io.EOF:
+			return resp, fmt.Errorf("connection closed, unexpected response: %q", resp)
+		case err != nil && resp == "":
+			return resp, fmt.Errorf("no response, err: %v", err)
+		case err != nil:
+			return resp, fmt.Errorf("unexpected response: %q, err: %v", resp, err)
+		case udp || len(resp) == maxResponse:
+			return resp, fmt.Errorf("unexpected response: %q", resp)
+		}
+	}
+}
 
+// ms returns duration in whole milliseconds.
+func ms(d time.Duration) int64 {
+	return d.Nanoseconds() / 1000 / 1000
 }
@@ -0,0 +1,90 @@
+package port
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+// serveTCP starts line server, which greets with banner and answers
+// PING with +PONG.
+func serveTCP(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte("220 test ready\r\n"))
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == "PING\r\n" {
+						conn.Write([]byte("+PONG\r\n"))
+					}
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// serveUDP starts echo server.
+func serveUDP(t *testing.T) string {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pc.WriteTo(buf[:n], addr)
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+func TestPort_Send(t *testing.T) {
+	tcp := serveTCP(t)
+	udp := serveUDP(t)
+	tests := []struct {
+		name     string
+		c        shared.CheckConfig
+		wantCode int
+	}{
+		{"connect", shared.CheckConfig{Check: tcp}, 200},
+		{"banner", shared.CheckConfig{Check: tcp, Expect: "220 "}, 200},
+		{"ping pong", shared.CheckConfig{Check: tcp, Payload: "PING\r\n", Expect: "+PONG"}, 200},
+		{"regex", shared.CheckConfig{Check: tcp, Payload: "PING\r\n", ExpectRegex: `(?m)^\+PONG`}, 200},
+		{"unexpected", shared.CheckConfig{Check: tcp, Payload: "PING\r\n", Expect: "-ERR"}, 500},
+		{"udp echo", shared.CheckConfig{Check: udp, Protocol: "udp", Payload: "hello", Expect: "hello"}, 200},
+		{"udp unexpected", shared.CheckConfig{Check: udp, Protocol: "udp", Payload: "hello", Expect: "bye"}, 500},
+	}
+	p := New(shared.Duration{Duration: 500 * time.Millisecond})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, _, err := p.Send(tt.c)
+			if got != tt.wantCode {
+				t.Errorf("Port.Send() code = %d, want %d, err: %v", got, tt.wantCode, err)
+			}
+		})
+	}
+}
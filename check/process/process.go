@@ -0,0 +1,216 @@
+// Package process implements local process worker. Processes are found
+// by name, command line regex or pidfile, reading /proc directly.
+package process
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernierasta/zorix/check/metrics"
+	"github.com/ernierasta/zorix/shared"
+)
+
+// clockTicks is USER_HZ, cpu times in /proc/<pid>/stat are in these units.
+const clockTicks = 100
+
+// Process worker
+type Process struct {
+	proc string // /proc mount point
+}
+
+// New return new Process worker instance
+func New() *Process {
+	return &Process{proc: "/proc"}
+}
+
+// stat is resource usage of one process.
+type stat struct {
+	pid     int
+	rss     float64 // MB
+	cpuTime float64 // seconds (user + system)
+}
+
+// Send finds processes and checks their count and resources.
+// Returns returnCode, metrics, time and error.
+func (p *Process) Send(c shared.CheckConfig) (int, string, int64, error) {
+	code, body, duration, _, err := p.SendVars(c)
+	return code, body, duration, err
+}
+
+// SendVars finds processes by pidfile, cmdline regex or name (c.Check)
+// and checks their count, rss and cpu_time. If more processes match,
+// maximal rss and cpu_time are used.
+// Returns returnCode, metrics, time, vars {count}, {pids}, {rss}, {cpu_time}
+// and error.
+// For convince success returns code 200 and errors:
+//   - no process found (and min_count > 0): 404
+//   - count out of min_count, max_count range (nil is not checked): 500
+//   - value over fail_at threshold, /proc read error: 500
+//
+// Value over warn_at threshold returns shared.Warning, which is processed as slowdown.
+func (p *Process) SendVars(c shared.CheckConfig) (int, string, int64, map[string]string, error) {
+	t0 := time.Now()
+	stats, err := p.find(c)
+	duration := time.Since(t0).Nanoseconds() / 1000 / 1000
+	if err != nil {
+		return 500, "", duration, nil, fmt.Errorf("process.Send: %v", err)
+	}
+
+	v := metrics.Values{"count": float64(len(stats))}
+	pids := []string{}
+	for _, s := range stats {
+		pids = append(pids, strconv.Itoa(s.pid))
+		if s.rss > v["rss"] {
+			v["rss"] = s.rss
+		}
+		if s.cpuTime > v["cpu_time"] {
+			v["cpu_time"] = s.cpuTime
+		}
+	}
+	vars := v.Vars()
+	vars["pids"] = strings.Join(pids, ",")
+	body := v.String() + "\npids: " + vars["pids"]
+
+	switch {
+	case c.MinCount != nil && len(stats) == 0 && *c.MinCount > 0:
+		return 404, body, duration, vars, fmt.Errorf("process.Send: no process %s found", name(c))
+	case c.MinCount != nil && len(stats) < *c.MinCount:
+		return 500, body, duration, vars, fmt.Errorf("process.Send: %d processes %s found, expected at least %d", len(stats), name(c), *c.MinCount)
+	case c.MaxCount != nil && len(stats) > *c.MaxCount:
+		return 500, body, duration, vars, fmt.Errorf("process.Send: %d processes %s found, expected at most %d", len(stats), name(c), *c.MaxCount)
+	}
+
+	if err := metrics.Check(v, &c); err != nil {
+		if shared.IsWarning(err) {
+			return 200, body, duration, vars, shared.NewWarning("process.Send: %s: %v", name(c), err)
+		}
+		return 500, body, duration, vars, fmt.Errorf("process.Send: %s: %v", name(c), err)
+	}
+	return 200, body, duration, vars, nil
+}
+
+// find returns stats of matching processes sorted by pid.
+// Missing pidfile or pid which is not running is not an error,
+// no process is found.
+func (p *Process) find(c shared.CheckConfig) ([]stat, error) {
+	if c.Pidfile != "" {
+		b, err := ioutil.ReadFile(c.Pidfile)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+		if err != nil {
+			return nil, fmt.Errorf("wrong pid in %s: %q", c.Pidfile, strings.TrimSpace(string(b)))
+		}
+		s, err := p.stat(pid)
+		if err != nil {
+			return nil, nil // not running
+		}
+		return []stat{s}, nil
+	}
+
+	var re *regexp.Regexp
+	if c.Cmdline != "" {
+		var err error
+		if re, err = regexp.Compile(c.Cmdline); err != nil {
+			return nil, err
+		}
+	}
+	dirs, err := ioutil.ReadDir(p.proc)
+	if err != nil {
+		return nil, err
+	}
+	self := os.Getpid()
+	stats := []stat{}
+	for _, d := range dirs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		if !p.match(pid, c.Check, re) {
+			continue
+		}
+		s, err := p.stat(pid)
+		if err != nil {
+			continue // process exited meanwhile or zombie
+		}
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].pid < stats[j].pid })
+	return stats, nil
+}
+
+// match returns true if process command line matches re, or (without re)
+// if process name (comm) or basename of executable is name.
+func (p *Process) match(pid int, name string, re *regexp.Regexp) bool {
+	b, err := ioutil.ReadFile(filepath.Join(p.proc, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return false
+	}
+	args := strings.Split(strings.TrimRight(string(b), "\x00"), "\x00")
+	if re != nil {
+		return len(b) > 0 && re.MatchString(strings.Join(args, " "))
+	}
+	if len(b) > 0 && filepath.Base(args[0]) == name {
+		return true
+	}
+	comm, err := ioutil.ReadFile(filepath.Join(p.proc, strconv.Itoa(pid), "comm"))
+	return err == nil && strings.TrimSpace(string(comm)) == name
+}
+
+// stat reads /proc/<pid>/stat. Zombie processes are returned as error.
+func (p *Process) stat(pid int) (stat, error) {
+	b, err := ioutil.ReadFile(filepath.Join(p.proc, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return stat{}, err
+	}
+	// comm (2nd field) is in parentheses and may contain spaces
+	i := strings.LastIndex(string(b), ")")
+	if i == -1 {
+		return stat{}, fmt.Errorf("wrong stat format for pid %d", pid)
+	}
+	f := strings.Fields(string(b[i+1:]))
+	if len(f) < 22 {
+		return stat{}, fmt.Errorf("wrong stat format for pid %d", pid)
+	}
+	if f[0] == "Z" {
+		return stat{}, fmt.Errorf("pid %d is zombie", pid)
+	}
+	utime, err1 := strconv.ParseFloat(f[11], 64)
+	stime, err2 := strconv.ParseFloat(f[12], 64)
+	rss, err3 := strconv.ParseFloat(f[21], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return stat{}, fmt.Errorf("wrong stat format for pid %d", pid)
+	}
+	return stat{
+		pid:     pid,
+		rss:     round(rss * float64(os.Getpagesize()) / 1024 / 1024),
+		cpuTime: round((utime + stime) / clockTicks),
+	}, nil
+}
+
+// name returns matched processes for messages.
+func name(c shared.CheckConfig) string {
+	switch {
+	case c.Pidfile != "":
+		return "from " + c.Pidfile
+	case c.Cmdline != "":
+		return "matching " + c.Cmdline
+	}
+	return c.Check
+}
+
+// round rounds to 2 decimal places.
+func round(f float64) float64 {
+	return float64(int64(f*100+0.5)) / 100
+}
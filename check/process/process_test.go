@@ -0,0 +1,95 @@
+package process
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+// writeProc creates fake /proc/<pid> entry.
+func writeProc(t *testing.T, proc string, pid int, comm, cmdline, state string, cpuTicks, rssPages int) {
+	dir := filepath.Join(proc, strconv.Itoa(pid))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	ticks := strconv.Itoa(cpuTicks)
+	stat := strconv.Itoa(pid) + " (" + comm + ") " + state + " 1 1 1 0 -1 4194560 100 0 0 0 " +
+		ticks + " " + ticks + " 0 0 20 0 1 0 100 1000000 " + strconv.Itoa(rssPages) + " 0\n"
+	files := map[string]string{
+		"comm":    comm + "\n",
+		"cmdline": strings.Replace(cmdline, " ", "\x00", -1) + "\x00",
+		"stat":    stat,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func count(n int) *int {
+	return &n
+}
+
+func TestProcess_SendVars(t *testing.T) {
+	proc := t.TempDir()
+	writeProc(t, proc, 10, "nginx", "/usr/sbin/nginx -g daemon off;", "S", 500, 256)
+	writeProc(t, proc, 11, "nginx", "nginx: worker process", "S", 12000, 512)
+	writeProc(t, proc, 20, "my worker", "/usr/bin/python3 /opt/app/worker.py --queue mail", "R", 100, 1024)
+	writeProc(t, proc, 30, "defunct", "", "Z", 0, 0)
+	pidfile := filepath.Join(t.TempDir(), "worker.pid")
+	if err := ioutil.WriteFile(pidfile, []byte("20\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rss := strconv.FormatFloat(float64(512*os.Getpagesize())/1024/1024, 'f', -1, 64)
+
+	tests := []struct {
+		name      string
+		c         shared.CheckConfig
+		wantCode  int
+		wantErr   bool
+		wantCount string
+		wantPids  string
+	}{
+		{"name", shared.CheckConfig{Check: "nginx", MinCount: count(1)}, 200, false, "2", "10,11"},
+		{"comm with space", shared.CheckConfig{Check: "my worker", MinCount: count(1)}, 200, false, "1", "20"},
+		{"cmdline", shared.CheckConfig{Cmdline: `worker\.py .*mail`, MinCount: count(1)}, 200, false, "1", "20"},
+		{"pidfile", shared.CheckConfig{Pidfile: pidfile, MinCount: count(1)}, 200, false, "1", "20"},
+		{"missing pidfile", shared.CheckConfig{Pidfile: pidfile + ".old", MinCount: count(1)}, 404, true, "0", ""},
+		{"not found", shared.CheckConfig{Check: "apache2", MinCount: count(1)}, 404, true, "0", ""},
+		{"zombie", shared.CheckConfig{Check: "defunct", MinCount: count(1)}, 404, true, "0", ""},
+		{"too few", shared.CheckConfig{Check: "nginx", MinCount: count(3)}, 500, true, "2", "10,11"},
+		{"too many", shared.CheckConfig{Check: "nginx", MinCount: count(1), MaxCount: count(1)}, 500, true, "2", "10,11"},
+		{"must not run", shared.CheckConfig{Check: "nginx", MinCount: count(0), MaxCount: count(0)}, 500, true, "2", "10,11"},
+		{"not running", shared.CheckConfig{Check: "apache2", MinCount: count(0), MaxCount: count(0)}, 200, false, "0", ""},
+		{"zero to n", shared.CheckConfig{Check: "apache2", MinCount: count(0), MaxCount: count(5)}, 200, false, "0", ""},
+		{"no limits", shared.CheckConfig{Check: "apache2"}, 200, false, "0", ""},
+		{"cpu time", shared.CheckConfig{Check: "nginx", MinCount: count(1), FailAt: map[string]float64{"cpu_time": 200}}, 500, true, "2", "10,11"},
+		{"rss", shared.CheckConfig{Check: "nginx", MinCount: count(1), WarnAt: map[string]float64{"rss": 0.1}}, 200, true, "2", "10,11"},
+	}
+	p := &Process{proc: proc}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _, vars, err := p.SendVars(tt.c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Process.SendVars() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if code != tt.wantCode {
+				t.Errorf("Process.SendVars() code = %d, want %d", code, tt.wantCode)
+			}
+			if vars["count"] != tt.wantCount || vars["pids"] != tt.wantPids {
+				t.Errorf("Process.SendVars() count = %s, pids = %s, want %s, %s", vars["count"], vars["pids"], tt.wantCount, tt.wantPids)
+			}
+		})
+	}
+
+	_, _, _, vars, _ := p.SendVars(shared.CheckConfig{Check: "nginx", MinCount: count(1)})
+	if vars["cpu_time"] != "240" || vars["rss"] != rss {
+		t.Errorf("Process.SendVars() cpu_time = %s, rss = %s, want 240, %s", vars["cpu_time"], vars["rss"], rss)
+	}
+}
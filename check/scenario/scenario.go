@@ -0,0 +1,243 @@
+// Package scenario implements multi-step HTTP transaction worker.
+// Steps are run in order with shared cookie jar, values extracted
+// from responses can be used in later steps as {var}. Check tls and
+// network settings are used for all steps (as in web worker), check
+// auth only for steps with auth = true.
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ernierasta/zorix/check/dialer"
+	"github.com/ernierasta/zorix/check/web"
+	"github.com/ernierasta/zorix/jsonpath"
+	"github.com/ernierasta/zorix/shared"
+	"github.com/valyala/fasttemplate"
+)
+
+// Scenario worker
+type Scenario struct {
+	timeout time.Duration
+	web     *web.Web // used for authorization, keeps oauth2 tokens
+}
+
+// New return new Scenario worker instance
+func New(t shared.Duration) *Scenario {
+	return &Scenario{timeout: t.Duration, web: web.New(t, false)}
+}
+
+// Send runs all steps of scenario.
+// Returns returnCode, last response body, total time and error.
+func (s *Scenario) Send(c shared.CheckConfig) (int, string, int64, error) {
+	code, body, duration, _, err := s.SendVars(c)
+	return code, body, duration, err
+}
+
+// SendVars runs all steps of scenario.
+// Returns returnCode, last response body, total time, vars and error.
+// If all steps are ok, returns code 200, otherwise code returned by failed step
+// (0 if request failed). Error and vars {failed_step}, {steps_time}
+// say which step broke.
+func (s *Scenario) SendVars(c shared.CheckConfig) (int, string, int64, map[string]string, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return 0, "", 0, nil, fmt.Errorf("scenario.Send: can not create cookie jar, err: %v", err)
+	}
+	tlsConfig, err := web.TLSConfig(&c)
+	if err != nil {
+		return 0, "", 0, nil, fmt.Errorf("scenario.Send: %v", err)
+	}
+	d, err := dialer.New(&c, s.timeout)
+	if err != nil {
+		return 0, "", 0, nil, fmt.Errorf("scenario.Send: %v", err)
+	}
+	client := http.Client{
+		Jar: jar,
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+			Proxy:           d.Proxy(),
+			DialContext:     d.DialContext,
+		},
+		Timeout:       s.timeout,
+		CheckRedirect: web.RedirectGuard(c.Redirs),
+	}
+	// check auth is sent only where step asks for it, steps can go
+	// to other hosts, which must not get check credentials
+	authorize := func(r *http.Request) error {
+		return s.web.Authorize(r, &c, tlsConfig)
+	}
+
+	vars := map[string]string{}
+	times := []string{}
+	resultVars := map[string]string{"failed_step": "", "steps_time": ""}
+	var total time.Duration
+	body := ""
+
+	for i, step := range c.Steps {
+		name := stepName(i, step)
+		code, b, dur, err := s.runStep(&client, step, vars, authorize)
+		total += dur
+		body = b
+		times = append(times, fmt.Sprintf("%s: %d ms", name, ms(dur)))
+		resultVars["steps_time"] = strings.Join(times, ", ")
+		if err != nil {
+			resultVars["failed_step"] = name
+			return code, body, ms(total), resultVars, fmt.Errorf("scenario.Send: step %s failed after %d ms, err: %v", name, ms(dur), err)
+		}
+	}
+
+	return 200, body, ms(total), resultVars, nil
+}
+
+// runStep sends one step request, validates response and extracts vars.
+func (s *Scenario) runStep(client *http.Client, step shared.Step, vars map[string]string, authorize func(*http.Request) error) (int, string, time.Duration, error) {
+	sc := &shared.CheckConfig{
+		Check:   substituteURL(step.URL, vars),
+		Method:  step.Method,
+		Headers: substitute(step.Headers, vars),
+		Params:  substitute(step.Params, vars),
+	}
+	request, err := web.NewRequest(sc)
+	if err != nil {
+		return 0, "", 0, err
+	}
+	if step.Auth && request.Header.Get("Authorization") == "" {
+		if err := authorize(request); err != nil {
+			return 0, "", 0, err
+		}
+	}
+
+	t0 := time.Now()
+	resp, err := client.Do(request)
+	if err != nil {
+		return 0, "", time.Since(t0), err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	dur := time.Since(t0)
+	if err != nil {
+		return resp.StatusCode, "", dur, fmt.Errorf("can not read response, err: %v", err)
+	}
+	body := string(b)
+
+	if resp.StatusCode != step.ExpectedCode {
+		return resp.StatusCode, body, dur, fmt.Errorf("wrong response code %d, expected %d", resp.StatusCode, step.ExpectedCode)
+	}
+	if step.LookFor != "" && !strings.Contains(body, step.LookFor) {
+		return resp.StatusCode, body, dur, fmt.Errorf("response does not contain: %s", step.LookFor)
+	}
+	for _, e := range step.Extract {
+		val, err := extract(e, resp, body)
+		if err != nil {
+			return resp.StatusCode, body, dur, fmt.Errorf("can not extract %q, err: %v", e.Var, err)
+		}
+		vars[e.Var] = val
+	}
+	return resp.StatusCode, body, dur, nil
+}
+
+// extract returns value from response, based on extract definition
+// (json path, header or regex).
+func extract(e shared.Extract, resp *http.Response, body string) (string, error) {
+	switch {
+	case e.JSON != "":
+		doc, err := jsonpath.Decode(body) // big numbers (ids) stay exact
+		if err != nil {
+			return "", fmt.Errorf("response is not valid json, err: %v", err)
+		}
+		v, err := jsonpath.Lookup(doc, e.JSON)
+		if err != nil {
+			return "", err
+		}
+		switch val := v.(type) {
+		case string:
+			return val, nil
+		case json.Number:
+			return val.String(), nil
+		}
+		b, err := json.Marshal(v)
+		return string(b), err
+	case e.Header != "":
+		v := resp.Header.Get(e.Header)
+		if v == "" {
+			return "", fmt.Errorf("header %s not found", e.Header)
+		}
+		return v, nil
+	case e.Regex != "":
+		re, err := regexp.Compile(e.Regex)
+		if err != nil {
+			return "", err
+		}
+		m := re.FindStringSubmatch(body)
+		if m == nil {
+			return "", fmt.Errorf("regex %s does not match", e.Regex)
+		}
+		if len(m) > 1 {
+			return m[1], nil
+		}
+		return m[0], nil
+	}
+	return "", fmt.Errorf("one of json, header or regex has to be set")
+}
+
+// substitute replaces {var} tags with extracted values,
+// unknown tags are kept (f.e.: json params).
+// In json, tag found by fasttemplate can start with json braces
+// (`{"id": "{id}"}` gives tag `"id": "{id`), so only part after
+// last '{' is used as var name.
+func substitute(s string, vars map[string]string) string {
+	return substituteEscaped(s, vars, func(v string) string { return v })
+}
+
+// substituteURL replaces {var} tags in url, values are escaped,
+// so they can not change url structure (f.e.: add query params).
+func substituteURL(u string, vars map[string]string) string {
+	path, query := u, ""
+	if i := strings.Index(u, "?"); i != -1 {
+		path, query = u[:i], u[i:]
+	}
+	return substituteEscaped(path, vars, url.PathEscape) + substituteEscaped(query, vars, url.QueryEscape)
+}
+
+// substituteEscaped replaces {var} tags with escaped values.
+func substituteEscaped(s string, vars map[string]string, escape func(string) string) string {
+	if len(vars) == 0 || s == "" {
+		return s
+	}
+	t, err := fasttemplate.NewTemplate(s, "{", "}")
+	if err != nil {
+		return s
+	}
+	return t.ExecuteFuncString(func(w io.Writer, tag string) (int, error) {
+		prefix, name := "", tag
+		if i := strings.LastIndex(tag, "{"); i != -1 {
+			prefix, name = "{"+tag[:i], tag[i+1:]
+		}
+		if v, ok := vars[name]; ok {
+			return w.Write([]byte(prefix + escape(v)))
+		}
+		return w.Write([]byte("{" + tag + "}"))
+	})
+}
+
+// stepName returns step number and name if defined.
+func stepName(i int, step shared.Step) string {
+	if step.Name != "" {
+		return fmt.Sprintf("%d (%s)", i+1, step.Name)
+	}
+	return fmt.Sprintf("%d", i+1)
+}
+
+// ms returns duration in whole milliseconds.
+func ms(d time.Duration) int64 {
+	return d.Nanoseconds() / 1000 / 1000
+}
@@ -0,0 +1,175 @@
+package scenario
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+func Test_substitute(t *testing.T) {
+	vars := map[string]string{"token": "abc", "id": "42"}
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"empty", "", ""},
+		{"header", "Authorization: Bearer {token}", "Authorization: Bearer abc"},
+		{"url", "https://example.com/items/{id}?t={token}", "https://example.com/items/42?t=abc"},
+		{"json kept", `{"id": "{id}"}`, `{"id": "42"}`},
+		{"nested json", `{"a": {"b": "{id}"}, "c": "{token}"}`, `{"a": {"b": "42"}, "c": "abc"}`},
+		{"unknown kept", "{unknown}", "{unknown}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := substitute(tt.s, vars); got != tt.want {
+				t.Errorf("substitute() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// loginServer emulates login -> token -> api call flow. Login requires
+// basic auth, following steps need session cookie set by login.
+func loginServer() *http.ServeMux {
+	session := func(r *http.Request) bool {
+		c, err := r.Cookie("session")
+		return err == nil && c.Value == "s1"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); r.Method != "POST" || !ok || user != "zorix" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/"})
+		w.Header().Set("X-Request-Id", "r42")
+		fmt.Fprint(w, `{"data": {"token": "abc", "id": 1234567890123456789}}`)
+	})
+	mux.HandleFunc("/csrf", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			http.Error(w, "credentials leaked", http.StatusBadRequest)
+			return
+		}
+		if !session(r) {
+			http.Error(w, "no session", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `<input name="csrf" value="x9">`)
+	})
+	mux.HandleFunc("/items/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/items/1234567890123456789" || !session(r) || r.Header.Get("Authorization") != "Bearer abc" ||
+			r.Header.Get("X-Csrf") != "x9" || r.Header.Get("X-Request-Id") != "r42" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"items": [1, 2]}`)
+	})
+	return mux
+}
+
+func loginSteps(url string) []shared.Step {
+	return []shared.Step{
+		{Name: "login", Method: "POST", URL: url + "/login", Params: `{"user": "zorix"}`, ExpectedCode: 200, Auth: true,
+			Extract: []shared.Extract{{Var: "token", JSON: "$.data.token"}, {Var: "id", JSON: "$.data.id"}, {Var: "rid", Header: "X-Request-Id"}}},
+		{Name: "csrf", URL: url + "/csrf", ExpectedCode: 200,
+			Extract: []shared.Extract{{Var: "csrf", Regex: `value="(\w+)"`}}},
+		{Name: "items", URL: url + "/items/{id}", ExpectedCode: 200, LookFor: "items", Auth: true,
+			Headers: "Authorization: Bearer {token}\nX-Csrf: {csrf}\nX-Request-Id: {rid}"},
+	}
+}
+
+func TestScenario_SendVars(t *testing.T) {
+	ts := httptest.NewServer(loginServer())
+	t.Cleanup(ts.Close)
+	tlsTs := httptest.NewTLSServer(loginServer())
+	t.Cleanup(tlsTs.Close)
+
+	noCookie := loginSteps(ts.URL)
+	noCookie[1].URL = strings.Replace(noCookie[1].URL, "127.0.0.1", "localhost", 1) // cookie is not sent to other host
+	noAuth := loginSteps(ts.URL)
+	noAuth[0].Auth = false
+	leak := loginSteps(ts.URL)
+	leak[1].Auth = true
+	badExtract := loginSteps(ts.URL)
+	badExtract[1].Extract[0].Regex = `token="(\w+)"`
+
+	tests := []struct {
+		name           string
+		c              shared.CheckConfig
+		wantCode       int
+		wantBody       string
+		wantFailedStep string
+		wantErr        bool
+	}{
+		{"ok", shared.CheckConfig{Steps: loginSteps(ts.URL), AuthUser: "zorix", AuthPass: "secret"}, 200, `{"items": [1, 2]}`, "", false},
+		{"tls ignore_cert", shared.CheckConfig{Steps: loginSteps(tlsTs.URL), AuthUser: "zorix", AuthPass: "secret", IgnoreCert: true}, 200, `{"items": [1, 2]}`, "", false},
+		{"tls unknown ca", shared.CheckConfig{Steps: loginSteps(tlsTs.URL), AuthUser: "zorix", AuthPass: "secret"}, 0, "", "1 (login)", true},
+		{"no check auth", shared.CheckConfig{Steps: loginSteps(ts.URL)}, 401, "unauthorized\n", "1 (login)", true},
+		{"step without auth", shared.CheckConfig{Steps: noAuth, AuthUser: "zorix", AuthPass: "secret"}, 401, "unauthorized\n", "1 (login)", true},
+		{"auth sent to step", shared.CheckConfig{Steps: leak, AuthUser: "zorix", AuthPass: "secret"}, 400, "credentials leaked\n", "2 (csrf)", true},
+		{"no cookie", shared.CheckConfig{Steps: noCookie, AuthUser: "zorix", AuthPass: "secret"}, 403, "no session\n", "2 (csrf)", true},
+		{"extract failed", shared.CheckConfig{Steps: badExtract, AuthUser: "zorix", AuthPass: "secret"}, 200, `<input name="csrf" value="x9">`, "2 (csrf)", true},
+	}
+	s := New(shared.Duration{Duration: 2 * time.Second})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body, _, vars, err := s.SendVars(tt.c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scenario.SendVars() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "step "+tt.wantFailedStep+" failed") {
+				t.Errorf("Scenario.SendVars() error = %v, want step %s", err, tt.wantFailedStep)
+			}
+			if code != tt.wantCode {
+				t.Errorf("Scenario.SendVars() code = %d, want %d", code, tt.wantCode)
+			}
+			if body != tt.wantBody {
+				t.Errorf("Scenario.SendVars() body = %q, want %q", body, tt.wantBody)
+			}
+			if vars["failed_step"] != tt.wantFailedStep {
+				t.Errorf("Scenario.SendVars() failed_step = %q, want %q", vars["failed_step"], tt.wantFailedStep)
+			}
+			if vars["steps_time"] == "" {
+				t.Errorf("Scenario.SendVars() steps_time is empty")
+			}
+		})
+	}
+}
+
+func Test_substituteURL(t *testing.T) {
+	vars := map[string]string{"id": "a/b c", "q": "x&y=z", "n": "42"}
+	tests := []struct {
+		name string
+		u    string
+		want string
+	}{
+		{"plain", "https://example.com/items/{n}?id={n}", "https://example.com/items/42?id=42"},
+		{"path escaped", "https://example.com/items/{id}", "https://example.com/items/a%2Fb%20c"},
+		{"query escaped", "https://example.com/search?q={q}&page=1", "https://example.com/search?q=x%26y%3Dz&page=1"},
+		{"unknown kept", "https://example.com/{unknown}", "https://example.com/{unknown}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := substituteURL(tt.u, vars); got != tt.want {
+				t.Errorf("substituteURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_extract_bigNumber(t *testing.T) {
+	got, err := extract(shared.Extract{Var: "id", JSON: "$.id"}, &http.Response{}, `{"id": 1234567890123456789, "ids": [1, 9007199254740993]}`)
+	if err != nil || got != "1234567890123456789" {
+		t.Errorf("extract() = %v, %v, want 1234567890123456789", got, err)
+	}
+	got, err = extract(shared.Extract{Var: "ids", JSON: "$.ids"}, &http.Response{}, `{"ids": [1, 9007199254740993]}`)
+	if err != nil || got != "[1,9007199254740993]" {
+		t.Errorf("extract() = %v, %v, want [1,9007199254740993]", got, err)
+	}
+}
@@ -0,0 +1,179 @@
+// Package sql implements database query worker (PostgreSQL, MySQL, SQLite).
+// Query result is returned as JSON, so it can be checked by 'assert'.
+// Connections are pooled per DSN and reused across intervals.
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+const (
+	// maxRows is max amount of rows in result, rows are counted all.
+	maxRows = 100
+	// maxConns is max amount of open connections per DSN.
+	maxConns = 2
+)
+
+// SQL worker
+type SQL struct {
+	timeout shared.Duration
+	mutex   sync.Mutex
+	pools   map[string]*sql.DB
+}
+
+// New return new SQL worker instance
+func New(timeout shared.Duration) *SQL {
+	return &SQL{timeout: timeout, pools: map[string]*sql.DB{}}
+}
+
+// result is JSON body returned by worker.
+type result struct {
+	Rows   int                      `json:"rows"`
+	Value  interface{}              `json:"value"`
+	Result []map[string]interface{} `json:"result"`
+}
+
+// Send runs query.
+// Returns returnCode, result as JSON, query time and error.
+func (s *SQL) Send(c shared.CheckConfig) (int, string, int64, error) {
+	code, body, duration, _, err := s.SendVars(c)
+	return code, body, duration, err
+}
+
+// SendVars runs query.
+// Returns returnCode, result as JSON, query time, vars {rows}, {value} and error.
+// JSON contains number of rows, first column of first row as value
+// and first 100 rows: {"rows": 1, "value": 5, "result": [{"lag": 5}]}.
+// For convince success returns code 200 and errors:
+//   - connection or query error: 500
+func (s *SQL) SendVars(c shared.CheckConfig) (int, string, int64, map[string]string, error) {
+	db, err := s.pool(c.Driver, c.DSN)
+	if err != nil {
+		return 500, "", 0, nil, fmt.Errorf("sql.Send: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout.Duration)
+	defer cancel()
+	t0 := time.Now()
+	res, err := query(ctx, db, c.Query)
+	duration := time.Since(t0).Nanoseconds() / 1000 / 1000
+	if err != nil {
+		return 500, "", duration, nil, fmt.Errorf("sql.Send: query %q failed, err: %v", c.Query, err)
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		return 500, "", duration, nil, fmt.Errorf("sql.Send: can not encode result, err: %v", err)
+	}
+	value, _ := json.Marshal(res.Value)
+	vars := map[string]string{"rows": strconv.Itoa(res.Rows), "value": string(value)}
+	if v, ok := res.Value.(string); ok {
+		vars["value"] = v
+	}
+	return 200, string(b), duration, vars, nil
+}
+
+// pool returns connection pool for DSN, it is created on first use.
+func (s *SQL) pool(driver, dsn string) (*sql.DB, error) {
+	key := driver + "|" + dsn
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if db, ok := s.pools[key]; ok {
+		return db, nil
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("can not open %s database, err: %v", driver, err)
+	}
+	db.SetMaxOpenConns(maxConns)
+	db.SetMaxIdleConns(maxConns)
+	s.pools[key] = db
+	return db, nil
+}
+
+// query runs query and converts rows.
+func query(ctx context.Context, db *sql.DB, q string) (result, error) {
+	res := result{Result: []map[string]interface{}{}}
+	rows, err := db.QueryContext(ctx, q)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return res, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return res, err
+	}
+	numeric := make([]bool, len(cols))
+	for i, t := range types {
+		numeric[i] = isNumeric(t.DatabaseTypeName())
+	}
+
+	for rows.Next() {
+		res.Rows++
+		if res.Rows > maxRows {
+			continue
+		}
+		vals := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return res, err
+		}
+		row := map[string]interface{}{}
+		for i, col := range cols {
+			row[col] = convert(vals[i], numeric[i])
+		}
+		if res.Rows == 1 && len(cols) > 0 {
+			res.Value = row[cols[0]]
+		}
+		res.Result = append(res.Result, row)
+	}
+	return res, rows.Err()
+}
+
+// isNumeric returns true for numeric database column types
+// (f.e.: INT4, BIGINT, NUMERIC, DECIMAL, DOUBLE).
+func isNumeric(dbType string) bool {
+	dbType = strings.ToUpper(dbType)
+	for _, t := range []string{"INT", "DEC", "NUMERIC", "FLOAT", "DOUBLE", "REAL"} {
+		if strings.Contains(dbType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// convert returns JSON friendly value. Drivers (f.e.: mysql, postgres numeric)
+// return numbers as []byte, they are converted to numbers only for numeric
+// columns. Number is kept as written by database, so big integers and
+// decimals do not lose precision. Text columns stay strings (f.e.: "007").
+func convert(v interface{}, numeric bool) interface{} {
+	switch val := v.(type) {
+	case []byte:
+		if _, err := strconv.ParseFloat(string(val), 64); numeric && err == nil {
+			return json.Number(val)
+		}
+		return string(val)
+	case time.Time:
+		return val.Format(time.RFC3339)
+	}
+	return v
+}
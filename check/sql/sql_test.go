@@ -0,0 +1,45 @@
+package sql
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func Test_convert(t *testing.T) {
+	tests := []struct {
+		name    string
+		v       interface{}
+		numeric bool
+		want    interface{}
+	}{
+		{"numeric bytes", []byte("12.50"), true, json.Number("12.50")},
+		{"numeric big int", []byte("9007199254740993"), true, json.Number("9007199254740993")},
+		{"numeric not number", []byte("1 day"), true, "1 day"},
+		{"text bytes", []byte("007"), false, "007"},
+		{"text", "1e5", false, "1e5"},
+		{"int", int64(9007199254740993), true, int64(9007199254740993)},
+		{"null", nil, false, nil},
+		{"time", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), false, "2020-01-02T03:04:05Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convert(tt.v, tt.numeric); got != tt.want {
+				t.Errorf("convert() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isNumeric(t *testing.T) {
+	for _, typ := range []string{"INT4", "BIGINT", "NUMERIC", "DECIMAL", "FLOAT8", "DOUBLE", "real", "UNSIGNED INT"} {
+		if !isNumeric(typ) {
+			t.Errorf("isNumeric(%q) = false, want true", typ)
+		}
+	}
+	for _, typ := range []string{"", "TEXT", "VARCHAR", "CHAR", "DATE", "BYTEA"} {
+		if isNumeric(typ) {
+			t.Errorf("isNumeric(%q) = true, want false", typ)
+		}
+	}
+}
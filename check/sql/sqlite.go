@@ -0,0 +1,7 @@
+//go:build cgo
+// +build cgo
+
+package sql
+
+// sqlite3 driver requires cgo, binary built without cgo does not support it.
+import _ "github.com/mattn/go-sqlite3"
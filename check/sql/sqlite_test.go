@@ -0,0 +1,44 @@
+//go:build cgo
+// +build cgo
+
+package sql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+func TestSQL_Send(t *testing.T) {
+	s := New(shared.Duration{Duration: 5 * time.Second})
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantBody string
+		wantErr  bool
+	}{
+		{"scalar", "SELECT 42 AS lag", 200, `{"rows":1,"value":42,"result":[{"lag":42}]}`, false},
+		{"rows", "SELECT 'a' AS name UNION ALL SELECT 'b'", 200, `{"rows":2,"value":"a","result":[{"name":"a"},{"name":"b"}]}`, false},
+		{"empty", "SELECT 1 WHERE 1 = 0", 200, `{"rows":0,"value":null,"result":[]}`, false},
+		{"text number", "SELECT '007' AS code, '1e5' AS version", 200, `{"rows":1,"value":"007","result":[{"code":"007","version":"1e5"}]}`, false},
+		{"big integer", "SELECT 9007199254740993 AS id", 200, `{"rows":1,"value":9007199254740993,"result":[{"id":9007199254740993}]}`, false},
+		{"error", "SELECT * FROM missing", 500, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := shared.CheckConfig{Driver: "sqlite3", DSN: ":memory:", Query: tt.query}
+			code, body, _, err := s.Send(c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SQL.Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if code != tt.wantCode || body != tt.wantBody {
+				t.Errorf("SQL.Send() = %d, %s, want %d, %s", code, body, tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+	if len(s.pools) != 1 {
+		t.Errorf("SQL.Send() created %d pools, want 1", len(s.pools))
+	}
+}
@@ -0,0 +1,344 @@
+// Package ssh implements remote resources worker (rtop like).
+// It logs in to host, reads /proc data and filesystem usage
+// and compares values with warn_at and fail_at thresholds.
+// Connections are reused, one per host and user.
+package ssh
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ernierasta/zorix/check/metrics"
+	"github.com/ernierasta/zorix/shared"
+	cryptossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	defaultPort = "22"
+	// separator splits outputs of remote commands.
+	separator = "--zorix--"
+)
+
+// command is run on remote host, outputs are separated by separator.
+var command = strings.Join([]string{
+	"cat /proc/loadavg",
+	"cat /proc/meminfo",
+	"LC_ALL=C df -P",
+	"LC_ALL=C df -Pi",
+	"cat /proc/uptime",
+	"cat /proc/net/dev",
+}, "; echo "+separator+"; ")
+
+// counter is network counters from previous run, used for rates.
+type counter struct {
+	rx, tx float64
+	t      time.Time
+}
+
+// SSH worker
+// Dials of the same host are serialized by per key lock,
+// so slow or unreachable host does not block other checks.
+type SSH struct {
+	timeout  shared.Duration
+	mutex    sync.Mutex
+	clients  map[string]*cryptossh.Client
+	counters map[string]counter
+	locks    map[string]*sync.Mutex
+}
+
+// New return new SSH worker instance
+func New(timeout shared.Duration) *SSH {
+	return &SSH{
+		timeout:  timeout,
+		clients:  map[string]*cryptossh.Client{},
+		counters: map[string]counter{},
+		locks:    map[string]*sync.Mutex{},
+	}
+}
+
+// Send reads metrics from remote host.
+// Returns returnCode, metrics, command time and error.
+func (s *SSH) Send(c shared.CheckConfig) (int, string, int64, error) {
+	code, body, duration, _, err := s.SendVars(c)
+	return code, body, duration, err
+}
+
+// SendVars reads metrics from remote host.
+// Returns returnCode, metrics, command time, metrics as vars and error.
+// For convince success returns code 200 and errors:
+//   - connection, login or command error: 500
+//   - value over fail_at threshold: 500
+//
+// Value over warn_at threshold returns shared.Warning, which is processed as slowdown.
+func (s *SSH) SendVars(c shared.CheckConfig) (int, string, int64, map[string]string, error) {
+	t0 := time.Now()
+	out, err := s.run(c)
+	duration := time.Since(t0).Nanoseconds() / 1000 / 1000
+	if err != nil {
+		return 500, "", duration, nil, fmt.Errorf("ssh.Send: %v", err)
+	}
+
+	v, err := parse(out, c.Mounts)
+	if err != nil {
+		return 500, out, duration, nil, fmt.Errorf("ssh.Send: can not parse output of %s, err: %v", c.Check, err)
+	}
+	s.rates(c.ID, v, t0)
+
+	if err := metrics.Check(v, withoutRates(c, v)); err != nil {
+		if shared.IsWarning(err) {
+			return 200, v.String(), duration, v.Vars(), shared.NewWarning("ssh.Send: %v", err)
+		}
+		return 500, v.String(), duration, v.Vars(), fmt.Errorf("ssh.Send: %v", err)
+	}
+	return 200, v.String(), duration, v.Vars(), nil
+}
+
+// run runs command on host, using cached connection. If session
+// can not be opened (f.e.: connection was closed), it reconnects once.
+func (s *SSH) run(c shared.CheckConfig) (string, error) {
+	client, err := s.client(c, false)
+	if err != nil {
+		return "", err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		client, err = s.client(c, true)
+		if err != nil {
+			return "", err
+		}
+		session, err = client.NewSession()
+		if err != nil {
+			return "", fmt.Errorf("can not open session, err: %v", err)
+		}
+	}
+	defer session.Close()
+
+	type result struct {
+		out []byte
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out, err := session.Output(command)
+		done <- result{out, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil && len(r.out) == 0 {
+			return "", fmt.Errorf("command failed, err: %v", r.err)
+		}
+		// some commands may fail (f.e.: df -Pi on busybox), it is solved during parsing
+		return string(r.out), nil
+	case <-time.After(s.timeout.Duration):
+		s.close(c)
+		return "", fmt.Errorf("command timed out after %s", s.timeout.Duration)
+	}
+}
+
+// client returns cached connection or dials new one.
+func (s *SSH) client(c shared.CheckConfig, reconnect bool) (*cryptossh.Client, error) {
+	key := c.AuthUser + "@" + address(c.Check)
+	unlock := s.lock(key)
+	defer unlock()
+	s.mutex.Lock()
+	client, ok := s.clients[key]
+	if ok && reconnect {
+		client.Close()
+		delete(s.clients, key)
+	}
+	s.mutex.Unlock()
+	if ok && !reconnect {
+		return client, nil
+	}
+
+	config, err := s.config(c)
+	if err != nil {
+		return nil, err
+	}
+	client, err = s.dial(address(c.Check), config)
+	if err != nil {
+		return nil, fmt.Errorf("can not connect to %s, err: %v", address(c.Check), err)
+	}
+	s.mutex.Lock()
+	s.clients[key] = client
+	s.mutex.Unlock()
+	return client, nil
+}
+
+// dial connects to addr, ssh handshake must finish within timeout
+// (config timeout covers only tcp connect).
+func (s *SSH) dial(addr string, config *cryptossh.ClientConfig) (*cryptossh.Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, s.timeout.Duration)
+	if err != nil {
+		return nil, err
+	}
+	if s.timeout.Duration > 0 {
+		conn.SetDeadline(time.Now().Add(s.timeout.Duration))
+	}
+	sc, chans, reqs, err := cryptossh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return cryptossh.NewClient(sc, chans, reqs), nil
+}
+
+// lock locks connection key and returns its unlock function.
+func (s *SSH) lock(key string) func() {
+	s.mutex.Lock()
+	l, ok := s.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[key] = l
+	}
+	s.mutex.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// close closes and forgets cached connection.
+func (s *SSH) close(c shared.CheckConfig) {
+	key := c.AuthUser + "@" + address(c.Check)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if client, ok := s.clients[key]; ok {
+		client.Close()
+		delete(s.clients, key)
+	}
+}
+
+// config returns client config with key and/or password auth.
+// Host key is verified using known_hosts file (~/.ssh/known_hosts
+// by default), unless ignore_cert is set.
+func (s *SSH) config(c shared.CheckConfig) (*cryptossh.ClientConfig, error) {
+	config := &cryptossh.ClientConfig{
+		User:    c.AuthUser,
+		Timeout: s.timeout.Duration,
+	}
+	if c.SSHKey != "" {
+		pem, err := ioutil.ReadFile(c.SSHKey)
+		if err != nil {
+			return nil, fmt.Errorf("can not read ssh_key, err: %v", err)
+		}
+		var signer cryptossh.Signer
+		if c.AuthPass != "" {
+			signer, err = cryptossh.ParsePrivateKeyWithPassphrase(pem, []byte(c.AuthPass))
+		} else {
+			signer, err = cryptossh.ParsePrivateKey(pem)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can not parse ssh_key %s, err: %v", c.SSHKey, err)
+		}
+		config.Auth = append(config.Auth, cryptossh.PublicKeys(signer))
+	} else if c.AuthPass != "" {
+		config.Auth = append(config.Auth, cryptossh.Password(c.AuthPass))
+	}
+
+	if c.IgnoreCert {
+		config.HostKeyCallback = cryptossh.InsecureIgnoreHostKey()
+		return config, nil
+	}
+	file := c.KnownHosts
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("can not find known_hosts, err: %v", err)
+		}
+		file = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	callback, err := knownhosts.New(file)
+	if err != nil {
+		return nil, fmt.Errorf("can not read known_hosts, err: %v", err)
+	}
+	config.HostKeyCallback = callback
+	return config, nil
+}
+
+// rates adds rx_rate and tx_rate (bytes/s) computed from previous run.
+func (s *SSH) rates(id string, v metrics.Values, t time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	prev, ok := s.counters[id]
+	s.counters[id] = counter{v["rx_bytes"], v["tx_bytes"], t}
+	if !ok {
+		return
+	}
+	sec := t.Sub(prev.t).Seconds()
+	if sec <= 0 || v["rx_bytes"] < prev.rx || v["tx_bytes"] < prev.tx {
+		return // counters reset (f.e.: reboot)
+	}
+	v["rx_rate"] = float64(int64((v["rx_bytes"] - prev.rx) / sec))
+	v["tx_rate"] = float64(int64((v["tx_bytes"] - prev.tx) / sec))
+}
+
+// withoutRates returns check without thresholds for rates, which are
+// not measured yet (first run, counters reset). Missing value is
+// failure in metrics.Check, but here it is expected.
+func withoutRates(c shared.CheckConfig, v metrics.Values) *shared.CheckConfig {
+	for _, rate := range []string{"rx_rate", "tx_rate"} {
+		if _, ok := v[rate]; ok {
+			continue
+		}
+		c.WarnAt = without(c.WarnAt, rate)
+		c.FailAt = without(c.FailAt, rate)
+		c.WarnBelow = without(c.WarnBelow, rate)
+		c.FailBelow = without(c.FailBelow, rate)
+	}
+	return &c
+}
+
+// without returns copy of thresholds without name.
+func without(thresholds map[string]float64, name string) map[string]float64 {
+	if _, ok := thresholds[name]; !ok {
+		return thresholds
+	}
+	res := map[string]float64{}
+	for k, v := range thresholds {
+		if k != name {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// parse parses command output. Inode usage is optional.
+func parse(out string, mounts []string) (metrics.Values, error) {
+	parts := strings.Split(out, separator+"\n")
+	if len(parts) != 6 {
+		return nil, fmt.Errorf("unexpected output: %q", out)
+	}
+	v := metrics.Values{}
+	if err := metrics.ParseLoadavg(parts[0], v); err != nil {
+		return nil, err
+	}
+	if err := metrics.ParseMeminfo(parts[1], v); err != nil {
+		return nil, err
+	}
+	if err := metrics.ParseDf(parts[2], "disk_used", mounts, v); err != nil {
+		return nil, err
+	}
+	metrics.ParseDf(parts[3], "inode_used", mounts, v)
+	if err := metrics.ParseUptime(parts[4], v); err != nil {
+		return nil, err
+	}
+	if err := metrics.ParseNetDev(parts[5], v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// address returns host:port, port 22 is used if not given.
+func address(check string) string {
+	if _, _, err := net.SplitHostPort(check); err != nil {
+		return net.JoinHostPort(check, defaultPort)
+	}
+	return check
+}
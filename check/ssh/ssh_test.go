@@ -0,0 +1,122 @@
+package ssh
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/check/metrics"
+	"github.com/ernierasta/zorix/shared"
+)
+
+var output = strings.Join([]string{
+	"0.50 0.40 0.30 1/100 1234\n",
+	"MemTotal: 1000 kB\nMemAvailable: 400 kB\nSwapTotal: 0 kB\nSwapFree: 0 kB\n",
+	"Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/sda1 100 50 50 50% /\n",
+	"df: unrecognized option: i\n",
+	"3600.5 7000.1\n",
+	"  eth0: 1000 10 0 0 0 0 0 0 2000 20 0 0 0 0 0 0\n",
+}, separator+"\n")
+
+func Test_parse(t *testing.T) {
+	v, err := parse(output, nil)
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+	want := metrics.Values{"load1": 0.5, "load5": 0.4, "load15": 0.3, "mem_used": 60, "swap_used": 0,
+		"disk_used": 50, "disk_used:/": 50, "uptime": 3600.5, "rx_bytes": 1000, "tx_bytes": 2000}
+	if v.String() != want.String() {
+		t.Errorf("parse() = %v, want %v", v, want)
+	}
+	if _, err := parse("not linux", nil); err == nil {
+		t.Errorf("parse() expected error for wrong output")
+	}
+}
+
+func TestSSH_rates(t *testing.T) {
+	s := New(shared.Duration{Duration: time.Second})
+	t0 := time.Now()
+	s.rates("id", metrics.Values{"rx_bytes": 1000, "tx_bytes": 1000}, t0)
+	v := metrics.Values{"rx_bytes": 3000, "tx_bytes": 1500}
+	s.rates("id", v, t0.Add(2*time.Second))
+	if v["rx_rate"] != 1000 || v["tx_rate"] != 250 {
+		t.Errorf("rates() = rx %v, tx %v, want 1000, 250", v["rx_rate"], v["tx_rate"])
+	}
+}
+
+func TestSSH_client_slowHost(t *testing.T) {
+	// silent host accepts connection, but never sends ssh banner
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	go func() {
+		for {
+			conn, err := silent.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	s := New(shared.Duration{Duration: 2 * time.Second})
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.client(shared.CheckConfig{Check: silent.Addr().String(), IgnoreCert: true}, false)
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	t0 := time.Now()
+	if _, err := s.client(shared.CheckConfig{Check: closed.Addr().String(), IgnoreCert: true}, false); err == nil {
+		t.Errorf("client() to closed port, want error")
+	}
+	if d := time.Since(t0); d > time.Second {
+		t.Errorf("client() blocked by slow host for %s", d)
+	}
+	if err := <-done; err == nil {
+		t.Errorf("client() to silent host, want timeout error")
+	}
+}
+
+func Test_withoutRates(t *testing.T) {
+	s := New(shared.Duration{Duration: time.Second})
+	c := shared.CheckConfig{
+		WarnAt:    map[string]float64{"load1": 4, "tx_rate": 100},
+		FailAt:    map[string]float64{"rx_rate": 500},
+		FailBelow: map[string]float64{"rx_rate": 1},
+	}
+	t0 := time.Now()
+
+	// first run, rates are not measured yet
+	v := metrics.Values{"load1": 0.5, "rx_bytes": 1000, "tx_bytes": 1000}
+	s.rates("id", v, t0)
+	if err := metrics.Check(v, withoutRates(c, v)); err != nil {
+		t.Errorf("metrics.Check() first run error = %v, want nil", err)
+	}
+	if len(c.FailAt) != 1 || len(c.WarnAt) != 2 {
+		t.Errorf("withoutRates() changed check thresholds: %v, %v", c.WarnAt, c.FailAt)
+	}
+
+	// second run, rates are checked
+	v = metrics.Values{"load1": 0.5, "rx_bytes": 3000, "tx_bytes": 1100}
+	s.rates("id", v, t0.Add(time.Second))
+	if err := metrics.Check(v, withoutRates(c, v)); err == nil || err.Error() != "rx_rate 2000 > 500" {
+		t.Errorf("metrics.Check() second run error = %v, want rx_rate failure", err)
+	}
+
+	// counters reset, rates are skipped again
+	v = metrics.Values{"load1": 0.5, "rx_bytes": 10, "tx_bytes": 10}
+	s.rates("id", v, t0.Add(2*time.Second))
+	if err := metrics.Check(v, withoutRates(c, v)); err != nil {
+		t.Errorf("metrics.Check() after reset error = %v, want nil", err)
+	}
+}
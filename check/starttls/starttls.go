@@ -0,0 +1,325 @@
+// Package starttls implements STARTTLS certificate worker.
+// It connects to plain text port, negotiates TLS using protocol
+// specific command (SMTP, IMAP, POP3, XMPP, LDAP, PostgreSQL)
+// and verifies certificates the same way as tls worker.
+package starttls
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	zorixtls "github.com/ernierasta/zorix/check/tls"
+	"github.com/ernierasta/zorix/shared"
+)
+
+// StartTLS worker
+type StartTLS struct {
+	timeout shared.Duration
+}
+
+// New return new StartTLS worker instance
+func New(timeout shared.Duration) *StartTLS {
+	return &StartTLS{timeout}
+}
+
+// Send negotiates STARTTLS with c.Check and verifies peer certificates.
+// Returns returnCode, certificates info, negotiation time and error.
+func (s *StartTLS) Send(c shared.CheckConfig) (int, string, int64, error) {
+	code, body, duration, _, err := s.SendVars(c)
+	return code, body, duration, err
+}
+
+// SendVars negotiates STARTTLS with c.Check and verifies peer certificates.
+// Returns returnCode, certificates info, negotiation time, certificate vars and error.
+// For convince success returns code 200 and errors:
+//   - connection, negotiation or handshake error: 500
+//   - expired, invalid or hostname mismatch: 500
+//
+// If any certificate in chain expires within c.WarnDays, shared.Warning
+// is returned, which is processed as slowdown.
+func (s *StartTLS) SendVars(c shared.CheckConfig) (int, string, int64, map[string]string, error) {
+	protocol, err := shared.StartTLSProtocol(c.Check, c.Protocol)
+	if err != nil {
+		return 500, "", 0, nil, fmt.Errorf("starttls.Send: %v", err)
+	}
+	host, _, err := net.SplitHostPort(c.Check)
+	if err != nil {
+		return 500, "", 0, nil, fmt.Errorf("starttls.Send: wrong address %q, err: %v", c.Check, err)
+	}
+
+	t0 := time.Now()
+	conn, err := net.DialTimeout("tcp", c.Check, s.timeout.Duration)
+	if err != nil {
+		return 500, "", 0, nil, fmt.Errorf("starttls.Send: can not connect to %s, err: %v", c.Check, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(t0.Add(s.timeout.Duration))
+
+	config := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true, // verified by tls.Verify, we want certificate info even if invalid
+	}
+	state, err := negotiate(conn, protocol, host, config)
+	duration := time.Since(t0).Nanoseconds() / 1000 / 1000
+	if err != nil {
+		return 500, "", duration, nil, fmt.Errorf("starttls.Send: %s STARTTLS with %s failed, err: %v", protocol, c.Check, err)
+	}
+
+	code, body, duration, vars, err := zorixtls.Verify(state.PeerCertificates, host, c.WarnDays, duration)
+	if err != nil && !shared.IsWarning(err) {
+		err = fmt.Errorf("starttls.Send: %v", err)
+	}
+	return code, body, duration, vars, err
+}
+
+// negotiate switches plain connection to TLS and returns connection state.
+func negotiate(conn net.Conn, protocol, host string, config *tls.Config) (tls.ConnectionState, error) {
+	if protocol == "smtp" {
+		return smtpStartTLS(conn, host, config)
+	}
+
+	var err error
+	r := bufio.NewReader(conn)
+	switch protocol {
+	case "imap":
+		err = imapStartTLS(conn, r)
+	case "pop3":
+		err = pop3StartTLS(conn, r)
+	case "xmpp":
+		err = xmppStartTLS(conn, r, host)
+	case "ldap":
+		err = ldapStartTLS(conn, r)
+	case "postgres":
+		err = postgresStartTLS(conn, r)
+	default:
+		err = fmt.Errorf("unknown protocol %q", protocol)
+	}
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		return tls.ConnectionState{}, err
+	}
+	return tlsConn.ConnectionState(), nil
+}
+
+// smtpStartTLS uses net/smtp client, the same way as mail notification.
+func smtpStartTLS(conn net.Conn, host string, config *tls.Config) (tls.ConnectionState, error) {
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	if err := c.Hello(hostname); err != nil {
+		return tls.ConnectionState{}, err
+	}
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		return tls.ConnectionState{}, fmt.Errorf("server does not support STARTTLS")
+	}
+	if err := c.StartTLS(config); err != nil {
+		return tls.ConnectionState{}, err
+	}
+	state, _ := c.TLSConnectionState()
+	c.Quit()
+	return state, nil
+}
+
+// imapStartTLS reads greeting and sends tagged STARTTLS command (RFC 3501).
+func imapStartTLS(w io.Writer, r *bufio.Reader) error {
+	if _, err := expectLine(r, "* OK"); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "z1 STARTTLS\r\n"); err != nil {
+		return err
+	}
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(line, "z1 ") {
+			if !strings.HasPrefix(line, "z1 OK") {
+				return fmt.Errorf("unexpected response: %q", line)
+			}
+			return nil
+		}
+	}
+}
+
+// pop3StartTLS reads greeting and sends STLS command (RFC 2595).
+func pop3StartTLS(w io.Writer, r *bufio.Reader) error {
+	if _, err := expectLine(r, "+OK"); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "STLS\r\n"); err != nil {
+		return err
+	}
+	_, err := expectLine(r, "+OK")
+	return err
+}
+
+// xmppStartTLS opens client stream and requests TLS (RFC 6120, section 5).
+func xmppStartTLS(w io.Writer, r *bufio.Reader, host string) error {
+	header := fmt.Sprintf("<?xml version='1.0'?><stream:stream to='%s' xmlns='jabber:client' "+
+		"xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>", host)
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	features, err := readUntil(r, "</stream:features>")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(features, "urn:ietf:params:xml:ns:xmpp-tls") {
+		return fmt.Errorf("server does not support STARTTLS")
+	}
+	if _, err := io.WriteString(w, "<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"); err != nil {
+		return err
+	}
+	resp, err := readUntil(r, ">")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(resp, "<proceed") {
+		return fmt.Errorf("unexpected response: %q", resp)
+	}
+	return nil
+}
+
+// ldapStartTLSRequest is BER encoded ExtendedRequest with StartTLS
+// OID 1.3.6.1.4.1.1466.20037 (RFC 4511, section 4.14), message ID 1.
+var ldapStartTLSRequest = append([]byte{0x30, 0x1d, 0x02, 0x01, 0x01, 0x77, 0x18, 0x80, 0x16},
+	[]byte("1.3.6.1.4.1.1466.20037")...)
+
+// ldapStartTLS sends StartTLS extended request and checks result code.
+func ldapStartTLS(w io.Writer, r *bufio.Reader) error {
+	if _, err := w.Write(ldapStartTLSRequest); err != nil {
+		return err
+	}
+	msg, err := readBER(r)
+	if err != nil {
+		return err
+	}
+	code, err := ldapResultCode(msg)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("server returned result code %d", code)
+	}
+	return nil
+}
+
+// ldapResultCode returns result code from ExtendedResponse message content
+// (message ID, [APPLICATION 24] { resultCode ENUMERATED, ... }).
+func ldapResultCode(msg []byte) (int, error) {
+	r := bufio.NewReader(bytes.NewReader(msg))
+	id, err := readBER(r) // message ID
+	if err != nil || len(id) == 0 {
+		return 0, fmt.Errorf("wrong response, missing message id")
+	}
+	tag, err := r.ReadByte()
+	if err != nil || tag != 0x78 {
+		return 0, fmt.Errorf("wrong response, expected extended response")
+	}
+	r.UnreadByte()
+	resp, err := readBER(r)
+	if err != nil {
+		return 0, err
+	}
+	if len(resp) < 3 || resp[0] != 0x0a || resp[1] != 0x01 {
+		return 0, fmt.Errorf("wrong response, missing result code")
+	}
+	return int(resp[2]), nil
+}
+
+// readBER reads one BER element and returns its content.
+func readBER(r *bufio.Reader) ([]byte, error) {
+	if _, err := r.ReadByte(); err != nil { // tag
+		return nil, err
+	}
+	l, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length := int(l)
+	if l&0x80 != 0 {
+		n := int(l & 0x7f)
+		if n == 0 || n > 4 {
+			return nil, fmt.Errorf("unsupported BER length")
+		}
+		length = 0
+		for i := 0; i < n; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	content := make([]byte, length)
+	_, err = io.ReadFull(r, content)
+	return content, err
+}
+
+// postgresStartTLS sends SSLRequest message, server answers
+// with single byte 'S' if it supports TLS.
+func postgresStartTLS(w io.Writer, r *bufio.Reader) error {
+	req := make([]byte, 8)
+	binary.BigEndian.PutUint32(req[0:4], 8)
+	binary.BigEndian.PutUint32(req[4:8], 80877103)
+	if _, err := w.Write(req); err != nil {
+		return err
+	}
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if b != 'S' {
+		return fmt.Errorf("server does not support TLS")
+	}
+	return nil
+}
+
+// expectLine reads line and checks its prefix.
+func expectLine(r *bufio.Reader, prefix string) (string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return line, err
+	}
+	if !strings.HasPrefix(line, prefix) {
+		return line, fmt.Errorf("unexpected response: %q", line)
+	}
+	return line, nil
+}
+
+// readLine reads line without line ending.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// readUntil reads until s is found.
+func readUntil(r *bufio.Reader, s string) (string, error) {
+	buf := ""
+	for !strings.Contains(buf, s) {
+		b, err := r.ReadByte()
+		if err != nil {
+			return buf, err
+		}
+		buf += string(b)
+	}
+	return buf, nil
+}
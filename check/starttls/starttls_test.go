@@ -0,0 +1,51 @@
+package starttls
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+)
+
+// fakeServer writes greeting, waits for request and writes response.
+func fakeServer(conn net.Conn, greeting, response string, requestLen int) {
+	defer conn.Close()
+	if greeting != "" {
+		conn.Write([]byte(greeting))
+	}
+	buf := make([]byte, requestLen)
+	if _, err := conn.Read(buf); err != nil {
+		return
+	}
+	conn.Write([]byte(response))
+}
+
+func Test_negotiation(t *testing.T) {
+	tests := []struct {
+		name      string
+		negotiate func(io.Writer, *bufio.Reader) error
+		greeting  string
+		response  string
+		wantErr   bool
+	}{
+		{"imap", imapStartTLS, "* OK ready\r\n", "z1 OK begin TLS\r\n", false},
+		{"imap refused", imapStartTLS, "* OK ready\r\n", "z1 BAD no\r\n", true},
+		{"pop3", pop3StartTLS, "+OK ready\r\n", "+OK begin TLS\r\n", false},
+		{"pop3 refused", pop3StartTLS, "+OK ready\r\n", "-ERR no\r\n", true},
+		{"postgres", postgresStartTLS, "", "S", false},
+		{"postgres refused", postgresStartTLS, "", "N", true},
+		{"ldap", ldapStartTLS, "", "\x30\x0c\x02\x01\x01\x78\x07\x0a\x01\x00\x04\x00\x04\x00", false},
+		{"ldap refused", ldapStartTLS, "", "\x30\x0c\x02\x01\x01\x78\x07\x0a\x01\x02\x04\x00\x04\x00", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			go fakeServer(server, tt.greeting, tt.response, 64)
+			err := tt.negotiate(client, bufio.NewReader(client))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("negotiation error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
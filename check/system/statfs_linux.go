@@ -0,0 +1,19 @@
+package system
+
+import "syscall"
+
+// statfs returns usage of filesystem mounted on path. Total blocks are
+// counted without reserved ones (the same as df).
+func statfs(path string) (usage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return usage{}, err
+	}
+	used := float64(st.Blocks - st.Bfree)
+	return usage{
+		diskUsed:   used,
+		diskTotal:  used + float64(st.Bavail),
+		inodeUsed:  float64(st.Files - st.Ffree),
+		inodeTotal: float64(st.Files),
+	}, nil
+}
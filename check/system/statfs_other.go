@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package system
+
+import "fmt"
+
+// statfs is supported only on linux.
+func statfs(path string) (usage, error) {
+	return usage{}, fmt.Errorf("statfs is not supported on this system")
+}
@@ -0,0 +1,110 @@
+// Package system implements local resources worker. It reads /proc
+// and statfs of filesystems on host, where zorix runs,
+// and compares values with warn_at and fail_at thresholds.
+package system
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/ernierasta/zorix/check/metrics"
+	"github.com/ernierasta/zorix/shared"
+)
+
+// System worker
+type System struct {
+	proc string // /proc mount point
+}
+
+// New return new System worker instance
+func New() *System {
+	return &System{proc: "/proc"}
+}
+
+// Send measures local resources.
+// Returns returnCode, metrics, measure time and error.
+func (s *System) Send(c shared.CheckConfig) (int, string, int64, error) {
+	code, body, duration, _, err := s.SendVars(c)
+	return code, body, duration, err
+}
+
+// SendVars measures local resources.
+// Returns returnCode, metrics, measure time, metrics as vars and error.
+// For convince success returns code 200 and errors:
+//   - /proc or statfs read error: 500
+//   - value over fail_at threshold: 500
+//
+// Value over warn_at threshold returns shared.Warning, which is processed as slowdown.
+func (s *System) SendVars(c shared.CheckConfig) (int, string, int64, map[string]string, error) {
+	t0 := time.Now()
+	v, err := s.measure(c.Mounts)
+	duration := time.Since(t0).Nanoseconds() / 1000 / 1000
+	if err != nil {
+		return 500, "", duration, nil, fmt.Errorf("system.Send: %v", err)
+	}
+
+	if err := metrics.Check(v, &c); err != nil {
+		if shared.IsWarning(err) {
+			return 200, v.String(), duration, v.Vars(), shared.NewWarning("system.Send: %v", err)
+		}
+		return 500, v.String(), duration, v.Vars(), fmt.Errorf("system.Send: %v", err)
+	}
+	return 200, v.String(), duration, v.Vars(), nil
+}
+
+// measure reads all metrics. If mounts is empty, all mounted
+// devices are measured and unreadable ones are skipped.
+func (s *System) measure(mounts []string) (metrics.Values, error) {
+	v := metrics.Values{}
+	parsers := []struct {
+		file  string
+		parse func(string, metrics.Values) error
+	}{
+		{"loadavg", metrics.ParseLoadavg},
+		{"meminfo", metrics.ParseMeminfo},
+		{"sys/fs/file-nr", metrics.ParseFileNr},
+	}
+	for _, p := range parsers {
+		b, err := ioutil.ReadFile(filepath.Join(s.proc, p.file))
+		if err != nil {
+			return nil, err
+		}
+		if err := p.parse(string(b), v); err != nil {
+			return nil, err
+		}
+	}
+	metrics.LoadPerCore(v, runtime.NumCPU())
+
+	all := len(mounts) == 0
+	if all {
+		b, err := ioutil.ReadFile(filepath.Join(s.proc, "mounts"))
+		if err != nil {
+			return nil, err
+		}
+		mounts = metrics.ParseMounts(string(b))
+	}
+	for _, m := range mounts {
+		u, err := statfs(m)
+		if err != nil {
+			if all {
+				continue
+			}
+			return nil, fmt.Errorf("can not read filesystem %s, err: %v", m, err)
+		}
+		metrics.AddUsage(v, "disk_used", m, u.diskUsed, u.diskTotal)
+		metrics.AddUsage(v, "inode_used", m, u.inodeUsed, u.inodeTotal)
+	}
+	if _, ok := v["disk_used"]; !ok {
+		return nil, fmt.Errorf("no filesystem found")
+	}
+	return v, nil
+}
+
+// usage is filesystem usage in blocks and inodes.
+type usage struct {
+	diskUsed, diskTotal   float64
+	inodeUsed, inodeTotal float64
+}
@@ -0,0 +1,64 @@
+package system
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+func TestSystem_SendVars(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("statfs is supported only on linux")
+	}
+	proc := t.TempDir()
+	files := map[string]string{
+		"loadavg":        "0.50 0.40 0.30 1/100 1234\n",
+		"meminfo":        "MemTotal: 1000 kB\nMemAvailable: 250 kB\nSwapTotal: 0 kB\nSwapFree: 0 kB\n",
+		"sys/fs/file-nr": "1000\t0\t10000\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(proc, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		c           shared.CheckConfig
+		wantCode    int
+		wantErr     bool
+		wantWarning bool
+	}{
+		{"ok", shared.CheckConfig{Mounts: []string{"/"}, FailAt: map[string]float64{"mem_used": 90}}, 200, false, false},
+		{"warning", shared.CheckConfig{Mounts: []string{"/"}, WarnAt: map[string]float64{"mem_used": 70}}, 200, true, true},
+		{"failure", shared.CheckConfig{Mounts: []string{"/"}, FailAt: map[string]float64{"fds_used": 5}}, 500, true, false},
+		{"missing mount", shared.CheckConfig{Mounts: []string{"/nonexistent"}}, 500, true, false},
+	}
+	s := &System{proc: proc}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, _, vars, err := s.SendVars(tt.c)
+			if (err != nil) != tt.wantErr || shared.IsWarning(err) != tt.wantWarning {
+				t.Fatalf("System.SendVars() error = %v, wantErr %v, wantWarning %v", err, tt.wantErr, tt.wantWarning)
+			}
+			if code != tt.wantCode {
+				t.Errorf("System.SendVars() code = %d, want %d", code, tt.wantCode)
+			}
+			if code == 200 {
+				for _, name := range []string{"mem_used", "open_fds", "load1_core", "disk_used:/"} {
+					if _, ok := vars[name]; !ok {
+						t.Errorf("System.SendVars() var %s not found in %v", name, vars)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Package tls implements TLS certificate worker.
+// It connects to host:port, verifies peer chain and reports
+// days left until certificates expire.
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+const (
+	defaultPort = "443"
+	dateFormat  = "2.1.2006 15:04:05"
+)
+
+// rootCAs are trusted roots used by Verify, nil means system roots.
+// It is replaced only in tests.
+var rootCAs *x509.CertPool
+
+// TLS worker
+type TLS struct {
+	timeout shared.Duration
+}
+
+// New return new TLS worker instance
+func New(timeout shared.Duration) *TLS {
+	return &TLS{timeout}
+}
+
+// Send connects to c.Check and verifies peer certificates.
+// Returns returnCode, certificates info, handshake time and error.
+func (t *TLS) Send(c shared.CheckConfig) (int, string, int64, error) {
+	code, body, duration, _, err := t.SendVars(c)
+	return code, body, duration, err
+}
+
+// SendVars connects to c.Check and verifies peer certificates.
+// Returns returnCode, certificates info, handshake time, certificate vars and error.
+// For convince success returns code 200 and errors:
+//   - connection or handshake error: 500
+//   - expired, invalid or hostname mismatch: 500
+//
+// If any certificate in chain expires within c.WarnDays, shared.Warning
+// is returned, which is processed as slowdown.
+func (t *TLS) SendVars(c shared.CheckConfig) (int, string, int64, map[string]string, error) {
+	addr, host := hostPort(c.Check)
+
+	dialer := &net.Dialer{Timeout: t.timeout.Duration}
+	t0 := time.Now()
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true, // verified below, we want certificate info even if invalid
+	})
+	duration := time.Since(t0).Nanoseconds() / 1000 / 1000
+	if err != nil {
+		return 500, "", duration, nil, fmt.Errorf("tls.Send: handshake with %s failed, err: %v", addr, err)
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	return Verify(certs, host, c.WarnDays, duration)
+}
+
+// Verify verifies certificate chain for host and returns the same values as SendVars.
+// It is exported, so other workers (f.e.: STARTTLS) can reuse it.
+// Expiry warning is disabled if warnDays is nil or 0.
+func Verify(certs []*x509.Certificate, host string, warnDays *int, duration int64) (int, string, int64, map[string]string, error) {
+	if len(certs) == 0 {
+		return 500, "", duration, nil, fmt.Errorf("tls.Verify: no peer certificates returned by %s", host)
+	}
+
+	now := time.Now()
+	vars, body := certVars(certs, now)
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Intermediates: intermediates,
+		Roots:         rootCAs,
+		CurrentTime:   now,
+	})
+	if err != nil {
+		return 500, body, duration, vars, fmt.Errorf("tls.Verify: certificate for %s is not valid, err: %v", host, err)
+	}
+
+	if first := firstExpiring(certs); warnDays != nil && days(first.NotAfter, now) < *warnDays {
+		return 200, body, duration, vars, shared.NewWarning("tls.Verify: certificate %s for %s expires in %d days (%s)",
+			first.Subject.String(), host, days(first.NotAfter, now), first.NotAfter.Local().Format(dateFormat))
+	}
+
+	return 200, body, duration, vars, nil
+}
+
+// certVars returns template vars and human readable chain info.
+// cert_expiry and cert_issuer belong to leaf certificate,
+// cert_days is minimum for whole chain.
+func certVars(certs []*x509.Certificate, now time.Time) (map[string]string, string) {
+	leaf := certs[0]
+	vars := map[string]string{
+		"cert_expiry":  leaf.NotAfter.Local().Format(dateFormat),
+		"cert_issuer":  leaf.Issuer.String(),
+		"cert_subject": leaf.Subject.String(),
+		"cert_days":    strconv.Itoa(days(firstExpiring(certs).NotAfter, now)),
+	}
+
+	lines := []string{}
+	for _, cert := range certs {
+		lines = append(lines, fmt.Sprintf("%s, issuer: %s, expires: %s (%d days)",
+			cert.Subject.String(), cert.Issuer.String(), cert.NotAfter.Local().Format(dateFormat), days(cert.NotAfter, now)))
+	}
+	return vars, strings.Join(lines, "\n")
+}
+
+// firstExpiring returns certificate in chain, which expires first
+// (f.e.: intermediate can expire before leaf).
+// Self-signed root (if sent by server) is ignored.
+func firstExpiring(certs []*x509.Certificate) *x509.Certificate {
+	first := certs[0]
+	for _, cert := range certs[1:] {
+		if cert.Subject.String() == cert.Issuer.String() {
+			continue
+		}
+		if cert.NotAfter.Before(first.NotAfter) {
+			first = cert
+		}
+	}
+	return first
+}
+
+// days returns whole days between now and t, negative if t is in past.
+func days(t, now time.Time) int {
+	return int(t.Sub(now).Hours() / 24)
+}
+
+// hostPort returns address with port (443 if not given) and host name.
+func hostPort(check string) (string, string) {
+	host, _, err := net.SplitHostPort(check)
+	if err != nil {
+		return net.JoinHostPort(check, defaultPort), check
+	}
+	return check, host
+}
@@ -0,0 +1,137 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+func Test_hostPort(t *testing.T) {
+	tests := []struct {
+		name     string
+		check    string
+		wantAddr string
+		wantHost string
+	}{
+		{"without port", "google.com", "google.com:443", "google.com"},
+		{"with port", "mail.google.com:993", "mail.google.com:993", "mail.google.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, host := hostPort(tt.check)
+			if addr != tt.wantAddr {
+				t.Errorf("hostPort() addr = %v, want %v", addr, tt.wantAddr)
+			}
+			if host != tt.wantHost {
+				t.Errorf("hostPort() host = %v, want %v", host, tt.wantHost)
+			}
+		})
+	}
+}
+
+// testCert is generated certificate with key.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newCert generates certificate for host valid until notAfter,
+// signed by parent (self-signed CA if parent is nil).
+func newCert(t *testing.T, cn string, parent *testCert, notAfter time.Time) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	isCA := !strings.Contains(cn, ".")
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-30 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if !isCA {
+		tmpl.DNSNames = []string{cn}
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	days := func(d int) time.Time { return now.Add(time.Duration(d)*24*time.Hour + time.Hour) }
+	root := newCert(t, "zorix root", nil, days(3650))
+	inter := newCert(t, "zorix intermediate", root, days(365))
+	shortInter := newCert(t, "zorix short intermediate", root, days(5))
+	rootCAs = x509.NewCertPool()
+	rootCAs.AddCert(root.cert)
+	t.Cleanup(func() { rootCAs = nil })
+
+	valid := newCert(t, "example.com", inter, days(90))
+	expired := newCert(t, "example.com", inter, now.Add(-49*time.Hour))
+	soon := newCert(t, "example.com", inter, days(10))
+	longLeaf := newCert(t, "example.com", shortInter, days(90))
+
+	tests := []struct {
+		name        string
+		chain       []*testCert
+		host        string
+		warnDays    int
+		wantCode    int
+		wantDays    string
+		wantErr     string // substring, "" means no error
+		wantWarning bool
+	}{
+		{"valid", []*testCert{valid, inter}, "example.com", 14, 200, "90", "", false},
+		{"valid with root", []*testCert{valid, inter, root}, "example.com", 14, 200, "90", "", false},
+		{"expired leaf", []*testCert{expired, inter}, "example.com", 14, 500, "-2", "not valid", false},
+		{"hostname mismatch", []*testCert{valid, inter}, "other.com", 14, 500, "90", "not valid", false},
+		{"unknown root", []*testCert{valid}, "example.com", 14, 500, "90", "not valid", false},
+		{"leaf expires soon", []*testCert{soon, inter}, "example.com", 14, 200, "10", "certificate CN=example.com", true},
+		{"intermediate expires first", []*testCert{longLeaf, shortInter}, "example.com", 14, 200, "5", "certificate CN=zorix short intermediate", true},
+		{"warning disabled", []*testCert{longLeaf, shortInter}, "example.com", 0, 200, "5", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certs := []*x509.Certificate{}
+			for _, c := range tt.chain {
+				certs = append(certs, c.cert)
+			}
+			code, body, _, vars, err := Verify(certs, tt.host, &tt.warnDays, 1)
+			if code != tt.wantCode {
+				t.Errorf("Verify() code = %d, want %d", code, tt.wantCode)
+			}
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+			if shared.IsWarning(err) != tt.wantWarning {
+				t.Errorf("Verify() error = %v, want warning %v", err, tt.wantWarning)
+			}
+			if vars["cert_days"] != tt.wantDays {
+				t.Errorf("Verify() cert_days = %s, want %s", vars["cert_days"], tt.wantDays)
+			}
+			if len(strings.Split(body, "\n")) != len(tt.chain) {
+				t.Errorf("Verify() body = %q, want line per certificate", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package web
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ernierasta/zorix/check/dialer"
+	"github.com/ernierasta/zorix/shared"
+)
+
+const (
+	// tokenRefreshMargin is how long before expiry token is refreshed.
+	tokenRefreshMargin = 30 * time.Second
+)
+
+// token is cached OAuth2 access token.
+type token struct {
+	value   string
+	expires time.Time // zero if token does not expire
+}
+
+// tokenCache keeps OAuth2 tokens for all checks of worker.
+// Fetches of the same token are serialized by per key lock,
+// so slow token endpoint does not block other checks.
+type tokenCache struct {
+	mutex  sync.Mutex
+	tokens map[string]token
+	locks  map[string]*sync.Mutex
+}
+
+// lock locks token key and returns its unlock function.
+func (tc *tokenCache) lock(key string) func() {
+	tc.mutex.Lock()
+	if tc.locks == nil {
+		tc.locks = map[string]*sync.Mutex{}
+	}
+	l, ok := tc.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		tc.locks[key] = l
+	}
+	tc.mutex.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// get returns cached token, if it is valid for at least tokenRefreshMargin.
+func (tc *tokenCache) get(key string) (token, bool) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	t, ok := tc.tokens[key]
+	if !ok || !(t.expires.IsZero() || time.Now().Add(tokenRefreshMargin).Before(t.expires)) {
+		return token{}, false
+	}
+	return t, true
+}
+
+// set stores token, empty token removes it.
+func (tc *tokenCache) set(key string, t token) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	if tc.tokens == nil {
+		tc.tokens = map[string]token{}
+	}
+	if t.value == "" {
+		delete(tc.tokens, key)
+		return
+	}
+	tc.tokens[key] = t
+}
+
+// authorize adds Authorization header to request, based on check auth settings.
+// OAuth2 has priority, then bearer token, then basic auth.
+func (w *Web) authorize(r *http.Request, c *shared.CheckConfig, tlsConfig *tls.Config) error {
+	switch {
+	case c.OAuth2TokenURL != "":
+		t, err := w.oauth2Token(c, tlsConfig)
+		if err != nil {
+			return fmt.Errorf("web.Send: can not get oauth2 token from %s, err: %v", c.OAuth2TokenURL, err)
+		}
+		r.Header.Set("Authorization", "Bearer "+t)
+	case c.AuthToken != "":
+		r.Header.Set("Authorization", "Bearer "+c.AuthToken)
+	case c.AuthUser != "":
+		r.SetBasicAuth(c.AuthUser, c.AuthPass)
+	}
+	return nil
+}
+
+// Authorize adds Authorization header to request, tokens are cached in w.
+// It is used by other http based workers (f.e.: websocket).
+func (w *Web) Authorize(r *http.Request, c *shared.CheckConfig, tlsConfig *tls.Config) error {
+	return w.authorize(r, c, tlsConfig)
+}
+
+// oauth2Token returns cached token, if it is missing or is about
+// to expire, new one is fetched (client credentials grant).
+func (w *Web) oauth2Token(c *shared.CheckConfig, tlsConfig *tls.Config) (string, error) {
+	key := strings.Join([]string{c.OAuth2TokenURL, c.OAuth2ClientID, strings.Join(c.OAuth2Scopes, " ")}, "|")
+
+	unlock := w.tokens.lock(key)
+	defer unlock()
+	if t, ok := w.tokens.get(key); ok {
+		return t.value, nil
+	}
+
+	t, err := w.fetchToken(c, tlsConfig)
+	w.tokens.set(key, t) // removes token on error
+	if err != nil {
+		return "", err
+	}
+	return t.value, nil
+}
+
+// fetchToken requests new token from token url, using the same network
+// settings (proxy, source, ip_version, resolve) as the check.
+// Client credentials are sent using basic auth (RFC 6749, section 2.3.1).
+func (w *Web) fetchToken(c *shared.CheckConfig, tlsConfig *tls.Config) (token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.OAuth2Scopes) > 0 {
+		form.Set("scope", strings.Join(c.OAuth2Scopes, " "))
+	}
+	req, err := http.NewRequest("POST", c.OAuth2TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return token{}, err
+	}
+	req.Header.Set(contentType, formContentType)
+	req.Header.Set("Accept", jsonContentType)
+	req.SetBasicAuth(url.QueryEscape(c.OAuth2ClientID), url.QueryEscape(c.OAuth2ClientSecret))
+
+	d, err := dialer.New(c, w.timeout)
+	if err != nil {
+		return token{}, err
+	}
+	client := http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+			Proxy:           d.Proxy(),
+			DialContext:     d.DialContext,
+		},
+		Timeout: w.timeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return token{}, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return token{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return token{}, fmt.Errorf("token endpoint returned %d", resp.StatusCode)
+	}
+
+	tr := struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}{}
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return token{}, fmt.Errorf("can not parse token response, err: %v", err)
+	}
+	if tr.AccessToken == "" {
+		return token{}, fmt.Errorf("token response does not contain access_token")
+	}
+	t := token{value: tr.AccessToken}
+	if tr.ExpiresIn > 0 {
+		t.expires = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
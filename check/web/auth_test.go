@@ -0,0 +1,166 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+// tokenServer returns token endpoint, which issues numbered tokens
+// valid for expiresIn seconds and counts requests.
+func tokenServer(t *testing.T, expiresIn int) (*httptest.Server, *int32) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "zorix" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		i := atomic.AddInt32(&n, 1)
+		fmt.Fprintf(w, `{"access_token": "token%d", "token_type": "bearer", "expires_in": %d}`, i, expiresIn)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &n
+}
+
+func oauth2Check(tokenURL string) *shared.CheckConfig {
+	return &shared.CheckConfig{OAuth2TokenURL: tokenURL, OAuth2ClientID: "zorix", OAuth2ClientSecret: "secret"}
+}
+
+func TestWeb_oauth2Token(t *testing.T) {
+	tests := []struct {
+		name       string
+		expiresIn  int
+		wantTokens []string
+	}{
+		{"cached", 3600, []string{"token1", "token1", "token1"}},
+		{"never expires", 0, []string{"token1", "token1"}},
+		{"refreshed within margin", int(tokenRefreshMargin.Seconds()) - 1, []string{"token1", "token2", "token3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, n := tokenServer(t, tt.expiresIn)
+			w := New(shared.Duration{Duration: 5 * time.Second}, false)
+			for i, want := range tt.wantTokens {
+				got, err := w.oauth2Token(oauth2Check(ts.URL), nil)
+				if err != nil {
+					t.Fatalf("Web.oauth2Token() error = %v", err)
+				}
+				if got != want {
+					t.Errorf("Web.oauth2Token() %d. call = %s, want %s", i+1, got, want)
+				}
+			}
+			last := tt.wantTokens[len(tt.wantTokens)-1]
+			if fetched := fmt.Sprintf("token%d", atomic.LoadInt32(n)); fetched != last {
+				t.Errorf("Web.oauth2Token() last fetched %s, want %s", fetched, last)
+			}
+		})
+	}
+}
+
+func TestWeb_oauth2Token_expired(t *testing.T) {
+	ts, n := tokenServer(t, 3600)
+	w := New(shared.Duration{Duration: 5 * time.Second}, false)
+	c := oauth2Check(ts.URL)
+	if _, err := w.oauth2Token(c, nil); err != nil {
+		t.Fatalf("Web.oauth2Token() error = %v", err)
+	}
+	key := c.OAuth2TokenURL + "|zorix|"
+	w.tokens.set(key, token{value: "token1", expires: time.Now().Add(-time.Second)})
+	got, err := w.oauth2Token(c, nil)
+	if err != nil {
+		t.Fatalf("Web.oauth2Token() error = %v", err)
+	}
+	if got != "token2" || atomic.LoadInt32(n) != 2 {
+		t.Errorf("Web.oauth2Token() = %s after %d fetches, want token2 after 2", got, atomic.LoadInt32(n))
+	}
+}
+
+func TestWeb_oauth2Token_error(t *testing.T) {
+	ts, _ := tokenServer(t, 3600)
+	w := New(shared.Duration{Duration: 5 * time.Second}, false)
+	c := oauth2Check(ts.URL)
+	c.OAuth2ClientSecret = "wrong"
+	if _, err := w.oauth2Token(c, nil); err == nil {
+		t.Errorf("Web.oauth2Token() with wrong secret, want error")
+	}
+}
+
+func TestWeb_oauth2Token_proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String() // absolute url in proxy request
+		fmt.Fprint(w, `{"access_token": "proxied", "expires_in": 3600}`)
+	}))
+	defer proxy.Close()
+
+	w := New(shared.Duration{Duration: 5 * time.Second}, false)
+	c := oauth2Check("http://token.zorix.invalid/oauth/token")
+	c.Proxy = proxy.URL
+	got, err := w.oauth2Token(c, nil)
+	if err != nil {
+		t.Fatalf("Web.oauth2Token() error = %v", err)
+	}
+	if got != "proxied" || proxied != c.OAuth2TokenURL {
+		t.Errorf("Web.oauth2Token() = %s via proxy request %q, want proxied via %s", got, proxied, c.OAuth2TokenURL)
+	}
+}
+
+func TestWeb_oauth2Token_slowEndpoint(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, `{"access_token": "slow"}`)
+	}))
+	defer slow.Close()
+	defer close(release)
+	fast, _ := tokenServer(t, 3600)
+
+	w := New(shared.Duration{Duration: 5 * time.Second}, false)
+	go w.oauth2Token(oauth2Check(slow.URL), nil)
+	time.Sleep(50 * time.Millisecond) // slow fetch is in progress
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := w.oauth2Token(oauth2Check(fast.URL), nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Web.oauth2Token() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Web.oauth2Token() blocked by slow token endpoint of other check")
+	}
+}
+
+func TestWeb_authorize(t *testing.T) {
+	ts, _ := tokenServer(t, 3600)
+	tests := []struct {
+		name string
+		c    *shared.CheckConfig
+		want string
+	}{
+		{"none", &shared.CheckConfig{}, ""},
+		{"basic", &shared.CheckConfig{AuthUser: "zorix", AuthPass: "secret"}, "Basic em9yaXg6c2VjcmV0"},
+		{"token", &shared.CheckConfig{AuthToken: "abc", AuthUser: "zorix"}, "Bearer abc"},
+		{"oauth2 has priority", &shared.CheckConfig{OAuth2TokenURL: ts.URL, OAuth2ClientID: "zorix", OAuth2ClientSecret: "secret", AuthToken: "abc"}, "Bearer token1"},
+	}
+	w := New(shared.Duration{Duration: 5 * time.Second}, false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "http://example.com", nil)
+			if err := w.authorize(r, tt.c, nil); err != nil {
+				t.Fatalf("Web.authorize() error = %v", err)
+			}
+			if got := r.Header.Get("Authorization"); got != tt.want {
+				t.Errorf("Web.authorize() Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package web
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+// timings keeps duration of request phases. If request is redirected,
+// phases of all requests are summed up.
+type timings struct {
+	mutex                             sync.Mutex
+	dns, connect, tls, ttfb, transfer time.Duration
+	dnsStart, tlsStart, wrote, firstB time.Time
+	connectStart                      map[string]time.Time
+}
+
+func newTimings() *timings {
+	return &timings{connectStart: map[string]time.Time{}}
+}
+
+// trace returns httptrace.ClientTrace, which records request phases.
+// Connect phase is measured only for successful connection
+// (there may be more parallel attempts).
+func (t *timings) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mutex.Lock()
+			t.dnsStart = time.Now()
+			t.mutex.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mutex.Lock()
+			t.dns += time.Since(t.dnsStart)
+			t.mutex.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			t.mutex.Lock()
+			t.connectStart[network+addr] = time.Now()
+			t.mutex.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.mutex.Lock()
+			if err == nil {
+				t.connect += time.Since(t.connectStart[network+addr])
+			}
+			t.mutex.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.mutex.Lock()
+			t.tlsStart = time.Now()
+			t.mutex.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mutex.Lock()
+			t.tls += time.Since(t.tlsStart)
+			t.mutex.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mutex.Lock()
+			t.wrote = time.Now()
+			t.mutex.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.mutex.Lock()
+			t.firstB = time.Now()
+			t.ttfb += t.firstB.Sub(t.wrote)
+			t.mutex.Unlock()
+		},
+	}
+}
+
+// bodyRead marks end of body transfer.
+func (t *timings) bodyRead() {
+	t.mutex.Lock()
+	if !t.firstB.IsZero() {
+		t.transfer = time.Since(t.firstB)
+	}
+	t.mutex.Unlock()
+}
+
+// vars returns phases in ms as template variables.
+func (t *timings) vars() map[string]string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return map[string]string{
+		"time_dns":      strconv.FormatInt(ms(t.dns), 10),
+		"time_connect":  strconv.FormatInt(ms(t.connect), 10),
+		"time_tls":      strconv.FormatInt(ms(t.tls), 10),
+		"time_ttfb":     strconv.FormatInt(ms(t.ttfb), 10),
+		"time_transfer": strconv.FormatInt(ms(t.transfer), 10),
+	}
+}
+
+// slowPhase returns shared.Warning for first phase exceeding its threshold
+// from check config. Threshold 0 means not checked.
+func (t *timings) slowPhase(c *shared.CheckConfig) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	phases := []struct {
+		name      string
+		got       time.Duration
+		threshold int64
+	}{
+		{"dns lookup", t.dns, c.SlowDNS},
+		{"tcp connect", t.connect, c.SlowConnect},
+		{"tls handshake", t.tls, c.SlowTLS},
+		{"time to first byte", t.ttfb, c.SlowTTFB},
+		{"body transfer", t.transfer, c.SlowTransfer},
+	}
+	for _, p := range phases {
+		if p.threshold > 0 && ms(p.got) > p.threshold {
+			return shared.NewWarning("web.Send: slow %s: %d ms, expected: %d ms", p.name, ms(p.got), p.threshold)
+		}
+	}
+	return nil
+}
+
+// ms returns duration in whole milliseconds.
+func ms(d time.Duration) int64 {
+	return d.Nanoseconds() / 1000 / 1000
+}
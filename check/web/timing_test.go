@@ -0,0 +1,74 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+func TestWeb_SendVars_timings(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond) // ttfb
+		w.Write([]byte("first part"))
+		w.(http.Flusher).Flush()
+		time.Sleep(100 * time.Millisecond) // transfer
+		w.Write([]byte("second part"))
+	}))
+	defer ts.Close()
+
+	w := New(shared.Duration{Duration: 5 * time.Second}, false)
+	c := shared.CheckConfig{Check: ts.URL, Method: "GET"}
+	_, _, duration, vars, err := w.SendVars(c)
+	if err != nil {
+		t.Fatalf("Web.SendVars() error = %v", err)
+	}
+	for _, name := range []string{"time_dns", "time_connect", "time_tls", "time_ttfb", "time_transfer"} {
+		if _, err := strconv.Atoi(vars[name]); err != nil {
+			t.Errorf("Web.SendVars() var %s = %q, want number", name, vars[name])
+		}
+	}
+	ttfb, _ := strconv.ParseInt(vars["time_ttfb"], 10, 64)
+	transfer, _ := strconv.ParseInt(vars["time_transfer"], 10, 64)
+	if ttfb < 50 || transfer < 100 {
+		t.Errorf("Web.SendVars() time_ttfb = %d, time_transfer = %d, want >= 50, >= 100", ttfb, transfer)
+	}
+	// response time ends when headers arrive, body transfer is not included
+	if duration < 50 || duration >= 150 {
+		t.Errorf("Web.SendVars() time = %d ms, want time to headers (50 - 150 ms)", duration)
+	}
+}
+
+func Test_timings_slowPhase(t *testing.T) {
+	tm := &timings{
+		dns:      10 * time.Millisecond,
+		connect:  20 * time.Millisecond,
+		ttfb:     300 * time.Millisecond,
+		transfer: 5 * time.Millisecond,
+	}
+	tests := []struct {
+		name    string
+		c       shared.CheckConfig
+		wantErr bool
+	}{
+		{"no thresholds", shared.CheckConfig{}, false},
+		{"under thresholds", shared.CheckConfig{SlowDNS: 50, SlowConnect: 50, SlowTTFB: 500}, false},
+		{"equal threshold", shared.CheckConfig{SlowTTFB: 300}, false},
+		{"slow ttfb", shared.CheckConfig{SlowDNS: 50, SlowTTFB: 200}, true},
+		{"slow connect", shared.CheckConfig{SlowConnect: 10}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tm.slowPhase(&tt.c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("timings.slowPhase() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !shared.IsWarning(err) {
+				t.Errorf("timings.slowPhase() error = %v, want shared.Warning", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+// tlsConfig builds TLS settings for given check. Certificate is ignored
+// if worker (insecureweb type) or check ignores it. Files are read on every
+// request, so renewed certificates are used without restart.
+func (w *Web) tlsConfig(c *shared.CheckConfig) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: w.ignoreCert || c.IgnoreCert,
+	}
+
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("web.Send: can not read ca_file, err: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("web.Send: no certificates found in ca_file %s", c.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if c.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(c.ClientCert, c.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("web.Send: can not load client certificate, err: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// TLSConfig builds TLS settings for given check (ignore_cert, ca_file,
+// client_cert, client_key). It is used by other workers (f.e.: grpc).
+func TLSConfig(c *shared.CheckConfig) (*tls.Config, error) {
+	return (&Web{}).tlsConfig(c)
+}
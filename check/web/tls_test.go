@@ -0,0 +1,142 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+// testCert is generated certificate with key.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newCert generates certificate signed by parent (self-signed if parent is nil).
+func newCert(t *testing.T, cn string, parent *testCert, isCA bool) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write writes certificate and key PEM files to dir, returns their paths.
+func (tc *testCert) write(t *testing.T, dir, name string) (string, string) {
+	keyDer, err := x509.MarshalECPrivateKey(tc.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+".key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tc.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestWeb_SendVars_mutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "zorix test CA", nil, true)
+	server := newCert(t, "127.0.0.1", ca, false)
+	client := newCert(t, "zorix", ca, false)
+	otherCA := newCert(t, "other CA", nil, true)
+	wrongClient := newCert(t, "zorix", otherCA, false)
+
+	caFile, _ := ca.write(t, dir, "ca")
+	clientCert, clientKey := client.write(t, dir, "client")
+	wrongCert, wrongKey := wrongClient.write(t, dir, "wrong")
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello " + r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{server.der}, PrivateKey: server.key}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	tests := []struct {
+		name    string
+		c       shared.CheckConfig
+		wantErr bool
+	}{
+		{"custom CA and client pair", shared.CheckConfig{CAFile: caFile, ClientCert: clientCert, ClientKey: clientKey}, false},
+		{"system CA", shared.CheckConfig{ClientCert: clientCert, ClientKey: clientKey}, true},
+		{"missing client pair", shared.CheckConfig{CAFile: caFile}, true},
+		{"client pair from other CA", shared.CheckConfig{CAFile: caFile, ClientCert: wrongCert, ClientKey: wrongKey}, true},
+		{"mismatched key", shared.CheckConfig{CAFile: caFile, ClientCert: clientCert, ClientKey: wrongKey}, true},
+		{"missing ca_file", shared.CheckConfig{CAFile: filepath.Join(dir, "missing.pem"), ClientCert: clientCert, ClientKey: clientKey}, true},
+		{"ca_file without certificates", shared.CheckConfig{CAFile: clientKey, ClientCert: clientCert, ClientKey: clientKey}, true},
+	}
+	w := New(shared.Duration{Duration: 5 * time.Second}, false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.c.Check, tt.c.Method = ts.URL, "GET"
+			code, body, _, _, err := w.SendVars(tt.c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Web.SendVars() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (code != 200 || body != "hello zorix") {
+				t.Errorf("Web.SendVars() = %d, %q, want 200, \"hello zorix\"", code, body)
+			}
+		})
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCert(t, "zorix test CA", nil, true)
+	caFile, _ := ca.write(t, dir, "ca")
+	client := newCert(t, "zorix", ca, false)
+	clientCert, clientKey := client.write(t, dir, "client")
+
+	config, err := TLSConfig(&shared.CheckConfig{IgnoreCert: true, CAFile: caFile, ClientCert: clientCert, ClientKey: clientKey})
+	if err != nil {
+		t.Fatalf("TLSConfig() error = %v", err)
+	}
+	if !config.InsecureSkipVerify || config.RootCAs == nil || len(config.Certificates) != 1 {
+		t.Errorf("TLSConfig() = %+v, want ignored cert, custom CA and client certificate", config)
+	}
+}
@@ -4,14 +4,17 @@ package web
 import (
 	"bufio"
 	"bytes"
-	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"net/textproto"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/ernierasta/zorix/check/dialer"
 	"github.com/ernierasta/zorix/shared"
 )
 
@@ -25,25 +28,49 @@ const (
 type Web struct {
 	timeout    time.Duration
 	ignoreCert bool
+	tokens     tokenCache
 }
 
 // New return new web worker instance
 func New(t shared.Duration, ignoreCert bool) *Web {
-	return &Web{t.Duration, ignoreCert}
+	return &Web{timeout: t.Duration, ignoreCert: ignoreCert}
 }
 
 // Send web request to destination url
 func (w *Web) Send(c shared.CheckConfig) (int, string, int64, error) {
+	code, body, duration, _, err := w.SendVars(c)
+	return code, body, duration, err
+}
+
+// SendVars sends web request to destination url.
+// Returns also duration of request phases (dns, connect, tls, ttfb, transfer)
+// as vars. If any phase exceeds its threshold, shared.Warning is returned.
+func (w *Web) SendVars(c shared.CheckConfig) (int, string, int64, map[string]string, error) {
 
 	request, err := w.newRequest(&c)
 	if err != nil {
-		return 0, "", 0, err
+		return 0, "", 0, nil, err
 	}
 
+	tm := newTimings()
+	request = request.WithContext(httptrace.WithClientTrace(request.Context(), tm.trace()))
+
+	tlsConfig, err := w.tlsConfig(&c)
+	if err != nil {
+		return 0, "", 0, nil, err
+	}
+	d, err := dialer.New(&c, w.timeout)
+	if err != nil {
+		return 0, "", 0, nil, err
+	}
 	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: w.ignoreCert,
-		},
+		TLSClientConfig: tlsConfig,
+		Proxy:           d.Proxy(),
+		DialContext:     d.DialContext,
+	}
+
+	if err := w.authorize(request, &c, tlsConfig); err != nil {
+		return 0, "", 0, nil, err
 	}
 
 	client := http.Client{
@@ -55,23 +82,90 @@ func (w *Web) Send(c shared.CheckConfig) (int, string, int64, error) {
 	t0 := time.Now()
 	//resp, err := client.Get(c.Check)
 	resp, err := client.Do(request)
-	reqDur := time.Since(t0)
+	reqDur := time.Since(t0) // until headers arrived, body transfer is in time_transfer
 	if err != nil {
-		return 0, "", 0, err
+		return 0, "", 0, tm.vars(), err
 	}
+	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
+	tm.bodyRead()
+	vars := tm.vars()
 	if err != nil {
-		return 0, "", 0, fmt.Errorf("web.Test: can not read response")
+		return 0, "", 0, vars, fmt.Errorf("web.Test: can not read response")
 	}
-	if len(body) == 0 {
-		return 0, "", 0, fmt.Errorf("web.Test: returned body is empty")
+	finalURL := resp.Request.URL
+	vars["final_url"] = finalURL.String()
+
+	if err := checkFinalURL(finalURL, c.ExpectedFinalURL); err != nil {
+		return resp.StatusCode, string(body), ms(reqDur), vars, err
+	}
+	if err := checkHeaders(resp.Header, c.ExpectedHeaders); err != nil {
+		return resp.StatusCode, string(body), ms(reqDur), vars, err
 	}
-	defer resp.Body.Close()
 
-	return resp.StatusCode, string(body), reqDur.Nanoseconds() / 1000 / 1000, nil
+	return resp.StatusCode, string(body), ms(reqDur), vars, tm.slowPhase(&c)
 
 }
 
+// checkFinalURL compares url after redirections with expected one.
+// If expected contains scheme, whole url has to match (trailing slash is ignored),
+// otherwise only host (with port if given) is compared.
+func checkFinalURL(final *url.URL, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	if strings.Contains(expected, "://") {
+		if strings.TrimSuffix(final.String(), "/") != strings.TrimSuffix(expected, "/") {
+			return fmt.Errorf("web.Send: final url is %s, expected: %s", final, expected)
+		}
+		return nil
+	}
+	host := final.Hostname()
+	if strings.Contains(expected, ":") {
+		host = final.Host
+	}
+	if !strings.EqualFold(host, expected) {
+		return fmt.Errorf("web.Send: final url %s is not on %s", final, expected)
+	}
+	return nil
+}
+
+// checkHeaders verifies response headers. Header has to be present,
+// if Equals or Regex is given, at least one header value has to match.
+func checkHeaders(h http.Header, expected []shared.HeaderExpect) error {
+	for _, e := range expected {
+		values, ok := h[textproto.CanonicalMIMEHeaderKey(e.Name)]
+		if !ok {
+			return fmt.Errorf("web.Send: header %s not found", e.Name)
+		}
+		if e.Equals == "" && e.Regex == "" {
+			continue
+		}
+		var re *regexp.Regexp
+		if e.Regex != "" {
+			var err error
+			if re, err = regexp.Compile(e.Regex); err != nil {
+				return fmt.Errorf("web.Send: wrong regex for header %s, err: %v", e.Name, err)
+			}
+		}
+		matched := false
+		for _, v := range values {
+			if (e.Equals == "" || v == e.Equals) && (re == nil || re.MatchString(v)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			want := e.Equals
+			if re != nil {
+				want = re.String()
+			}
+			return fmt.Errorf("web.Send: header %s: %s does not match %s", e.Name, strings.Join(values, ", "), want)
+		}
+	}
+	return nil
+}
+
 // newRequest prepares http.Request with needed headers and body
 func (w *Web) newRequest(c *shared.CheckConfig) (*http.Request, error) {
 	request := &http.Request{}
@@ -104,6 +198,18 @@ func (w *Web) newRequest(c *shared.CheckConfig) (*http.Request, error) {
 	return request, nil
 }
 
+// NewRequest prepares http.Request for given check, c.Check is url.
+// It is used by other http based workers (f.e.: scenario).
+func NewRequest(c *shared.CheckConfig) (*http.Request, error) {
+	return (&Web{}).newRequest(c)
+}
+
+// RedirectGuard returns http.Client CheckRedirect function,
+// which allows max r redirects.
+func RedirectGuard(r int) func(req *http.Request, via []*http.Request) error {
+	return redirectGuard(r)
+}
+
 func redirectGuard(r int) func(req *http.Request, via []*http.Request) error {
 	return func(req *http.Request, via []*http.Request) error {
 		if len(via) > r {
@@ -134,6 +240,12 @@ func parseHeaders(s string) map[string][]string {
 	return map[string][]string{}
 }
 
+// ParseHeaders parses headers in config format ("Name: value" lines).
+// It is used by other workers (f.e.: grpc metadata).
+func ParseHeaders(s string) map[string][]string {
+	return parseHeaders(s)
+}
+
 // addHeaders adds headers to the request
 func addHeaders(r *http.Request, h map[string][]string) {
 	//r.Header = map[string][]string{} // NewRequest initializes this map, so not needed
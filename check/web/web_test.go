@@ -3,8 +3,10 @@ package web
 import (
 	"bytes"
 	"fmt"
-	"io"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"testing"
 	"time"
@@ -31,18 +33,20 @@ var (
 
 func TestNew(t *testing.T) {
 	type args struct {
-		t shared.Duration
+		t          shared.Duration
+		ignoreCert bool
 	}
 	tests := []struct {
 		name string
 		args args
 		want *Web
 	}{
-		// TODO: Add test cases.
+		{"secure", args{shared.Duration{Duration: time.Second}, false}, &Web{timeout: time.Second}},
+		{"insecure", args{shared.Duration{Duration: time.Second}, true}, &Web{timeout: time.Second, ignoreCert: true}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := New(tt.args.t); !reflect.DeepEqual(got, tt.want) {
+			if got := New(tt.args.t, tt.args.ignoreCert); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("New() = %v, want %v", got, tt.want)
 			}
 		})
@@ -71,7 +75,7 @@ func TestWeb_Send(t *testing.T) {
 			w := &Web{
 				timeout: tt.fields.timeout,
 			}
-			got, got1, err := w.Send(tt.args.c)
+			got, _, got1, err := w.Send(tt.args.c)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Web.Send() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -194,8 +198,9 @@ func TestWeb_newRequest(t *testing.T) {
 
 	req1 := &http.Request{
 		Method: "POST",
+		Proto:  "HTTP/1.1",
 		Header: map[string][]string{contentType: []string{jsonContentType}},
-		Body:   io.ReadCloser(bytes.NewBuffer([]byte(""))),
+		Body:   ioutil.NopCloser(bytes.NewBufferString(jsonCheck.Params)),
 	}
 
 	type fields struct {
@@ -230,8 +235,10 @@ func TestWeb_newRequest(t *testing.T) {
 			if !reflect.DeepEqual(got.Header, tt.want.Header) {
 				t.Errorf("Web.newRequest() = %v\nwant:%+v\n", got.Header, tt.want.Header)
 			}
-			if !reflect.DeepEqual(got.Body, tt.want.Body) {
-				t.Errorf("Web.newRequest() = %v\nwant:%+v\n", got.Body, tt.want.Body)
+			gotBody, _ := ioutil.ReadAll(got.Body)
+			wantBody, _ := ioutil.ReadAll(tt.want.Body)
+			if !bytes.Equal(gotBody, wantBody) {
+				t.Errorf("Web.newRequest() body = %s\nwant:%s\n", gotBody, wantBody)
 			}
 			if !reflect.DeepEqual(got.Proto, tt.want.Proto) {
 				t.Errorf("Web.newRequest() = %v\nwant:%+v\n", got.Proto, tt.want.Proto)
@@ -239,3 +246,99 @@ func TestWeb_newRequest(t *testing.T) {
 		})
 	}
 }
+
+func Test_checkHeaders(t *testing.T) {
+	h := http.Header{
+		"Cache-Control": {"no-cache", "max-age=0"},
+		"X-Version":     {"1.4.2"},
+	}
+	tests := []struct {
+		name     string
+		expected []shared.HeaderExpect
+		wantErr  bool
+	}{
+		{"nothing expected", nil, false},
+		{"present", []shared.HeaderExpect{{Name: "x-version"}}, false},
+		{"missing", []shared.HeaderExpect{{Name: "X-Frame-Options"}}, true},
+		{"equals second value", []shared.HeaderExpect{{Name: "Cache-Control", Equals: "max-age=0"}}, false},
+		{"not equals", []shared.HeaderExpect{{Name: "X-Version", Equals: "1.4"}}, true},
+		{"regex", []shared.HeaderExpect{{Name: "X-Version", Regex: `^1\.4\.\d+$`}}, false},
+		{"regex not matching", []shared.HeaderExpect{{Name: "X-Version", Regex: `^2\.`}}, true},
+		{"wrong regex", []shared.HeaderExpect{{Name: "X-Version", Regex: `(`}}, true},
+		{"all have to pass", []shared.HeaderExpect{{Name: "X-Version"}, {Name: "Server"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkHeaders(h, tt.expected); (err != nil) != tt.wantErr {
+				t.Errorf("checkHeaders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_checkFinalURL(t *testing.T) {
+	final, _ := url.Parse("https://www.example.com:8443/login/")
+	tests := []struct {
+		name     string
+		expected string
+		wantErr  bool
+	}{
+		{"not checked", "", false},
+		{"host", "www.example.com", false},
+		{"host case insensitive", "WWW.Example.com", false},
+		{"other host", "example.com", true},
+		{"host with port", "www.example.com:8443", false},
+		{"host with other port", "www.example.com:443", true},
+		{"full url", "https://www.example.com:8443/login", false},
+		{"other path", "https://www.example.com:8443/", true},
+		{"other scheme", "http://www.example.com:8443/login/", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkFinalURL(final, tt.expected); (err != nil) != tt.wantErr {
+				t.Errorf("checkFinalURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWeb_SendVars_emptyBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("X-Version", "1.4.2")
+		if r.Method == "HEAD" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		c        shared.CheckConfig
+		wantCode int
+		wantErr  bool
+	}{
+		{"204", shared.CheckConfig{Check: ts.URL, Method: "GET"}, 204, false},
+		{"HEAD", shared.CheckConfig{Check: ts.URL, Method: "HEAD"}, 200, false},
+		{"HEAD with header", shared.CheckConfig{Check: ts.URL, Method: "HEAD", ExpectedHeaders: []shared.HeaderExpect{{Name: "X-Version", Equals: "1.4.2"}}}, 200, false},
+		{"redirected", shared.CheckConfig{Check: ts.URL + "/old", Method: "GET", Redirs: 1, ExpectedFinalURL: ts.URL + "/new"}, 204, false},
+		{"wrong final url", shared.CheckConfig{Check: ts.URL + "/old", Method: "GET", Redirs: 1, ExpectedFinalURL: ts.URL + "/old"}, 204, true},
+	}
+	w := New(shared.Duration{Duration: 5 * time.Second}, false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body, _, _, err := w.SendVars(tt.c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Web.SendVars() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if code != tt.wantCode || body != "" {
+				t.Errorf("Web.SendVars() code = %d, body = %q, want %d, empty body", code, body, tt.wantCode)
+			}
+		})
+	}
+}
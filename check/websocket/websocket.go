@@ -0,0 +1,141 @@
+// Package websocket implements websocket worker. It performs upgrade
+// handshake, optionally sends message and waits for reply.
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ernierasta/zorix/check/dialer"
+	"github.com/ernierasta/zorix/check/web"
+	"github.com/ernierasta/zorix/shared"
+	ws "github.com/gorilla/websocket"
+)
+
+// maxMessage is max size of received message.
+const maxMessage = 1 << 20
+
+// WebSocket worker
+type WebSocket struct {
+	timeout time.Duration
+	web     *web.Web // used for authorization, keeps oauth2 tokens
+}
+
+// New return new WebSocket worker instance
+func New(t shared.Duration) *WebSocket {
+	return &WebSocket{timeout: t.Duration, web: web.New(t, false)}
+}
+
+// Send connects to websocket url and exchanges message.
+// Returns returnCode, reply, time and error.
+func (w *WebSocket) Send(c shared.CheckConfig) (int, string, int64, error) {
+	code, body, duration, _, err := w.SendVars(c)
+	return code, body, duration, err
+}
+
+// SendVars connects to websocket url (ws:// or wss://), sends message
+// from 'send' (if given) and waits for reply containing look_for
+// and matching look_for_regex. Other messages are skipped. Without
+// look_for and look_for_regex first message is the reply. If there is
+// nothing to send or look for, only handshake is checked.
+// Returns returnCode, reply, whole time, vars {time_handshake},
+// {time_roundtrip} (in ms) and error.
+// For convince success returns code 200 and errors:
+//   - handshake rejected by server: http status code (f.e.: 400, 401)
+//   - connection error, no matching reply in http_timeout: 500
+func (w *WebSocket) SendVars(c shared.CheckConfig) (int, string, int64, map[string]string, error) {
+	request, err := http.NewRequest("GET", c.Check, nil)
+	if err != nil {
+		return 500, "", 0, nil, fmt.Errorf("websocket.Send: wrong url %s, err: %v", c.Check, err)
+	}
+	for k, vv := range web.ParseHeaders(c.Headers) {
+		for _, v := range vv {
+			request.Header.Add(k, v)
+		}
+	}
+	tlsConfig, err := web.TLSConfig(&c)
+	if err != nil {
+		return 500, "", 0, nil, fmt.Errorf("websocket.Send: %v", err)
+	}
+	if err := w.web.Authorize(request, &c, tlsConfig); err != nil {
+		return 500, "", 0, nil, fmt.Errorf("websocket.Send: %v", err)
+	}
+	d, err := dialer.New(&c, w.timeout)
+	if err != nil {
+		return 500, "", 0, nil, fmt.Errorf("websocket.Send: %v", err)
+	}
+	wsDialer := ws.Dialer{
+		NetDialContext:   d.DialContext,
+		Proxy:            d.Proxy(),
+		TLSClientConfig:  tlsConfig,
+		HandshakeTimeout: w.timeout,
+	}
+
+	t0 := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+	conn, resp, err := wsDialer.DialContext(ctx, c.Check, request.Header)
+	handshake := time.Since(t0)
+	if err != nil {
+		if resp != nil {
+			return resp.StatusCode, "", ms(handshake), nil, fmt.Errorf("websocket.Send: handshake with %s failed, status: %s", c.Check, resp.Status)
+		}
+		return 500, "", ms(handshake), nil, fmt.Errorf("websocket.Send: can not connect to %s, err: %v", c.Check, err)
+	}
+	defer conn.Close()
+	conn.SetReadLimit(maxMessage)
+	conn.SetWriteDeadline(t0.Add(w.timeout))
+	conn.SetReadDeadline(t0.Add(w.timeout))
+
+	t1 := time.Now()
+	var reply string
+	if c.Payload != "" {
+		err = conn.WriteMessage(ws.TextMessage, []byte(c.Payload))
+	}
+	if err == nil && (c.Payload != "" || c.LookFor != "" || c.LookForRegex != "") {
+		reply, err = read(conn, c)
+	}
+	roundtrip := time.Since(t1)
+	vars := map[string]string{
+		"time_handshake": strconv.FormatInt(ms(handshake), 10),
+		"time_roundtrip": strconv.FormatInt(ms(roundtrip), 10),
+	}
+	if err != nil {
+		return 500, reply, ms(time.Since(t0)), vars, fmt.Errorf("websocket.Send: no reply from %s, err: %v", c.Check, err)
+	}
+	conn.WriteMessage(ws.CloseMessage, ws.FormatCloseMessage(ws.CloseNormalClosure, ""))
+	return 200, reply, ms(time.Since(t0)), vars, nil
+}
+
+// read reads messages until one contains look_for and matches
+// look_for_regex. On error last received message is returned.
+func read(conn *ws.Conn, c shared.CheckConfig) (string, error) {
+	var re *regexp.Regexp
+	if c.LookForRegex != "" {
+		var err error
+		if re, err = regexp.Compile(c.LookForRegex); err != nil {
+			return "", err
+		}
+	}
+	last := ""
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return last, err
+		}
+		last = string(msg)
+		if strings.Contains(last, c.LookFor) && (re == nil || re.MatchString(last)) {
+			return last, nil
+		}
+	}
+}
+
+// ms returns duration in whole milliseconds.
+func ms(d time.Duration) int64 {
+	return d.Nanoseconds() / 1000 / 1000
+}
@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ernierasta/zorix/shared"
+	ws "github.com/gorilla/websocket"
+)
+
+func TestWebSocket_SendVars(t *testing.T) {
+	upgrader := ws.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "zorix" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(ws.TextMessage, []byte("welcome"))
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(ws.TextMessage, []byte("heartbeat"))
+			conn.WriteMessage(ws.TextMessage, []byte("echo: "+string(msg)))
+		}
+	}))
+	t.Cleanup(ts.Close)
+	url := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	tests := []struct {
+		name      string
+		c         shared.CheckConfig
+		wantCode  int
+		wantReply string
+		wantErr   bool
+	}{
+		{"handshake only", shared.CheckConfig{Check: url, AuthUser: "zorix", AuthPass: "secret"}, 200, "", false},
+		{"unauthorized", shared.CheckConfig{Check: url}, 401, "", true},
+		{"first message", shared.CheckConfig{Check: url, AuthUser: "zorix", AuthPass: "secret", Payload: "ping"}, 200, "welcome", false},
+		{"look_for", shared.CheckConfig{Check: url, AuthUser: "zorix", AuthPass: "secret", Payload: "ping", LookFor: "echo"}, 200, "echo: ping", false},
+		{"look_for_regex", shared.CheckConfig{Check: url, AuthUser: "zorix", AuthPass: "secret", Payload: "ping", LookForRegex: "^echo: p"}, 200, "echo: ping", false},
+		{"no match", shared.CheckConfig{Check: url, AuthUser: "zorix", AuthPass: "secret", Payload: "ping", LookFor: "pong"}, 500, "echo: ping", true},
+	}
+	w := New(shared.Duration{Duration: 500 * time.Millisecond})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, reply, _, vars, err := w.SendVars(tt.c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WebSocket.SendVars() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if code != tt.wantCode {
+				t.Errorf("WebSocket.SendVars() code = %d, want %d", code, tt.wantCode)
+			}
+			if reply != tt.wantReply {
+				t.Errorf("WebSocket.SendVars() reply = %q, want %q", reply, tt.wantReply)
+			}
+			if code == 200 && (vars["time_handshake"] == "" || vars["time_roundtrip"] == "") {
+				t.Errorf("WebSocket.SendVars() vars = %v, want time_handshake and time_roundtrip", vars)
+			}
+		})
+	}
+}
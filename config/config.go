@@ -2,8 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/ernierasta/zorix/jsonpath"
 	"github.com/ernierasta/zorix/shared"
 	"github.com/ernierasta/zorix/template"
 
@@ -15,30 +21,54 @@ const (
 	HTTPTimeout = "60s"
 	PingTimeout = "60s"
 	PortTimeout = "5s"
-
-	CheckType         = "web"
-	CheckMethod       = "GET"
-	CheckRepeat       = "60s"
-	CheckExpectedCode = 200
-	CheckExpectedTime = 1000
-	CheckAllowedSlows = 3
-	CheckAllowedFails = 1
+	DNSTimeout  = "5s"
+	TLSTimeout  = "10s"
+	MailTimeout = "2m"
+	SSHTimeout  = "10s"
+	SQLTimeout  = "30s"
+	KVTimeout   = "5s"
+	GRPCTimeout = "10s"
+
+	CheckType             = "web"
+	CheckMethod           = "GET"
+	CheckRepeat           = "60s"
+	CheckExpectedCode     = 200
+	CheckExpectedTime     = 1000
+	CheckMailExpectedTime = 60000 // mail delivery takes seconds, polled every interval
+	CheckAllowedSlows     = 3
+	CheckAllowedFails     = 1
+	CheckDNSRecord        = "A"
+	CheckTLSWarnDays      = 14
+	CheckPingCount        = 1
+	CheckPingInterval     = "1s"
+	CheckPortProtocol     = "tcp"
+	CheckMailInterval     = "5s"
+	CheckProcessMinCount  = 1
 
 	NotifyType          = "mail"
-	NotifySubjectFail   = "{check}{params} problem"
-	NotifySubjectSlow   = "{check}{params} slow"
-	NotifySubjectFailOK = "{check}{params} ok"
-	NotifySubjectSlowOK = "{check}{params} ok"
-	NotifyTextFail      = "FAILURE:\n{check}{params}\nTime: {timestamp}\n\nResponse code: {response_code}\nError: {error}\n"
-	NotifyTextSlow      = "SLOW RESPONSE:\n{check}{params}\nTime: {timestamp}\n\nResponse/Expected time: {response_time}/{expected_time}\n"
-	NotifyTextFailOK    = "RECOVERED:\n{check}{params}\nTime: {timestamp}\n\nResponse code: {response_code}\n"
-	NotifyTextSlowOK    = "RECOVERED:\n{check}{params}\nTime: {timestamp}\n\nResponse/Expected time: {response_time}/{expected_time}\n"
+	NotifySubjectFail   = "{check}{params}{address} problem"
+	NotifySubjectSlow   = "{check}{params}{address} slow"
+	NotifySubjectFailOK = "{check}{params}{address} ok"
+	NotifySubjectSlowOK = "{check}{params}{address} ok"
+	NotifyTextFail      = "FAILURE:\n{check}{params}{address}\nTime: {timestamp}\n\nResponse code: {response_code}\nError: {error}\n"
+	NotifyTextSlow      = "SLOW RESPONSE:\n{check}{params}{address}\nTime: {timestamp}\n\nResponse/Expected time: {response_time}/{expected_time}\n"
+	NotifyTextFailOK    = "RECOVERED:\n{check}{params}{address}\nTime: {timestamp}\n\nResponse code: {response_code}\n"
+	NotifyTextSlowOK    = "RECOVERED:\n{check}{params}{address}\nTime: {timestamp}\n\nResponse/Expected time: {response_time}/{expected_time}\n"
 )
 
 var (
 	// notifTypes is slice of available notifications. Empty is also ok, will be normalized.
 	// Add new type here!
 	notifTypes = []string{"", "mail", "jabber", "cmd"}
+
+	// proxySchemes is slice of supported proxy url schemes.
+	proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+	// mailboxSchemes is slice of supported mailloop mailbox url schemes.
+	mailboxSchemes = []string{"imap", "imaps", "pop3", "pop3s"}
+
+	// dnsRecords is slice of supported dns record types. Empty will be normalized.
+	dnsRecords = []string{"", "A", "AAAA", "CNAME", "MX", "TXT", "NS"}
 )
 
 // Config represents whole configuration file parsed to stuct.
@@ -78,6 +108,9 @@ func (c *Config) validateGlobal() error {
 	if c.Global.Workers == 0 {
 		return fmt.Errorf("config.validate: [global] workers not defined (cur val: %d), fix config file", c.Global.Workers)
 	}
+	if err := validateProxy(c.Global.Proxy); err != nil {
+		return fmt.Errorf("config.validate: wrong [global] 'proxy', err: %v. fix config file", err)
+	}
 
 	return nil
 }
@@ -95,6 +128,127 @@ func (c *Config) validateChecks() error {
 		if check.Check == "" {
 			return fmt.Errorf("config.validate: empty 'check' for %q. check. This field is mandatory, fix config file", check.ID)
 		}
+		if check.Type == "dns" && !found(strings.ToUpper(check.Record), dnsRecords) {
+			return fmt.Errorf("config.validate: unknown 'record' %q for %q check, available: %s, fix config file", check.Record, check.ID, strings.Join(dnsRecords[1:], ", "))
+		}
+		if check.Type == "starttls" {
+			if _, err := shared.StartTLSProtocol(check.Check, check.Protocol); err != nil {
+				return fmt.Errorf("config.validate: wrong starttls %q check, err: %v. fix config file", check.ID, err)
+			}
+		}
+		if (check.Type == "tls" || check.Type == "starttls") && check.WarnDays != nil && *check.WarnDays < 0 {
+			return fmt.Errorf("config.validate: negative 'warn_days' for %q check, fix config file", check.ID)
+		}
+		if check.Type == "ping" && (check.Count < 0 || check.MaxLoss < 0 || check.MaxLoss > 100) {
+			return fmt.Errorf("config.validate: 'count' must be positive and 'max_loss' in range 0-100 for %q check, fix config file", check.ID)
+		}
+		if check.Type == "port" {
+			if err := validatePort(check); err != nil {
+				return err
+			}
+		}
+		if check.Type == "mailloop" {
+			if err := validateMailloop(check); err != nil {
+				return err
+			}
+		}
+		if check.Type == "sql" {
+			if !found(check.Driver, shared.SQLDrivers) {
+				return fmt.Errorf("config.validate: unknown 'driver' %q for %q check, available: %s, fix config file", check.Driver, check.ID, strings.Join(shared.SQLDrivers, ", "))
+			}
+			if check.DSN == "" || check.Query == "" {
+				return fmt.Errorf("config.validate: 'dsn' and 'query' are mandatory for sql %q check, fix config file", check.ID)
+			}
+		}
+		if check.Type == "grpc" {
+			if _, _, err := net.SplitHostPort(check.Check); err != nil {
+				return fmt.Errorf("config.validate: 'check' for grpc %q check has to be in form host:port, fix config file", check.ID)
+			}
+		}
+		if check.Type == "websocket" {
+			if u, err := url.Parse(check.Check); err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+				return fmt.Errorf("config.validate: 'check' for websocket %q check has to be ws:// or wss:// url, fix config file", check.ID)
+			}
+		}
+		if check.Type == "ssh" {
+			if err := validateSSH(check); err != nil {
+				return err
+			}
+		}
+		if check.Type == "system" {
+			if err := validateThresholds(check, shared.SystemMetrics); err != nil {
+				return err
+			}
+		}
+		if check.Type == "process" {
+			if err := validateProcess(check); err != nil {
+				return err
+			}
+		}
+		if check.Type == "file" {
+			if err := validateFile(check); err != nil {
+				return err
+			}
+		}
+		if check.Type == "scenario" {
+			if err := validateSteps(check); err != nil {
+				return err
+			}
+		}
+		if err := validateProxy(check.Proxy); err != nil {
+			return fmt.Errorf("config.validate: wrong 'proxy' for %q check, err: %v. fix config file", check.ID, err)
+		}
+		for _, r := range check.Resolve {
+			if _, _, err := shared.ParseResolve(r); err != nil {
+				return fmt.Errorf("config.validate: wrong 'resolve' for %q check, err: %v. fix config file", check.ID, err)
+			}
+		}
+		if check.FanOut && !found(check.Type, []string{"", "web", "insecureweb", "port"}) {
+			return fmt.Errorf("config.validate: 'fan_out' for %q check is supported only for web and port types, fix config file", check.ID)
+		}
+		// http proxy resolves host itself (CONNECT with host name), so pinned
+		// addresses would be silently ignored, port type connects to pinned address
+		proxy := check.Proxy
+		if proxy == "" {
+			proxy = c.Global.Proxy
+		}
+		if proxy != "" && !check.NoProxy && (check.FanOut || len(check.Resolve) > 0) &&
+			found(check.Type, []string{"", "web", "insecureweb", "websocket", "scenario"}) {
+			return fmt.Errorf("config.validate: 'fan_out' and 'resolve' can not be used with proxy for %q check, proxy resolves host itself, set no_proxy = true or remove proxy, fix config file", check.ID)
+		}
+		if check.IPVersion != 0 && check.IPVersion != 4 && check.IPVersion != 6 {
+			return fmt.Errorf("config.validate: 'ip_version' for %q check can be only 4 or 6, fix config file", check.ID)
+		}
+		if (check.ClientCert == "") != (check.ClientKey == "") {
+			return fmt.Errorf("config.validate: both 'client_cert' and 'client_key' have to be set for %q check, fix config file", check.ID)
+		}
+		if check.OAuth2TokenURL != "" && check.OAuth2ClientID == "" {
+			return fmt.Errorf("config.validate: empty 'oauth2_client_id' for %q check. It is mandatory with 'oauth2_token_url', fix config file", check.ID)
+		}
+		for _, expr := range check.ChangeIgnore {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("config.validate: wrong 'change_ignore' %q for %q check, err: %v. fix config file", expr, check.ID, err)
+			}
+		}
+		for _, h := range check.ExpectedHeaders {
+			if h.Name == "" {
+				return fmt.Errorf("config.validate: empty 'name' in 'header' of %q check. This field is mandatory, fix config file", check.ID)
+			}
+			if _, err := regexp.Compile(h.Regex); err != nil {
+				return fmt.Errorf("config.validate: wrong 'regex' for header %q of %q check, err: %v. fix config file", h.Name, check.ID, err)
+			}
+		}
+		for _, a := range check.Assertions {
+			if err := jsonpath.Validate(a); err != nil {
+				return fmt.Errorf("config.validate: wrong 'assert' for %q check, err: %v. fix config file", check.ID, err)
+			}
+		}
+		if _, err := regexp.Compile(check.LookForRegex); err != nil {
+			return fmt.Errorf("config.validate: wrong 'look_for_regex' for %q check, err: %v. fix config file", check.ID, err)
+		}
+		if _, err := regexp.Compile(check.MustNotMatch); err != nil {
+			return fmt.Errorf("config.validate: wrong 'must_not_match' for %q check, err: %v. fix config file", check.ID, err)
+		}
 		if check.NotifyFail != nil {
 			if err := c.validateNotifyIDList(check.NotifyFail); err != nil {
 				return fmt.Errorf("config.validate: wrong notification in 'notify_fail' for %q. check, err: %v. fix config file", check.ID, err)
@@ -109,6 +263,141 @@ func (c *Config) validateChecks() error {
 	return nil
 }
 
+// validatePort checks port protocol and send/expect settings.
+func validatePort(check shared.CheckConfig) error {
+	switch strings.ToLower(check.Protocol) {
+	case "", "tcp":
+	case "udp":
+		if check.Payload == "" {
+			return fmt.Errorf("config.validate: 'send' is mandatory for udp %q check, fix config file", check.ID)
+		}
+	default:
+		return fmt.Errorf("config.validate: unknown 'protocol' %q for %q check, available: tcp, udp, fix config file", check.Protocol, check.ID)
+	}
+	if check.ExpectRegex != "" {
+		if _, err := regexp.Compile(check.ExpectRegex); err != nil {
+			return fmt.Errorf("config.validate: wrong 'expect_regex' %q for %q check, err: %v. fix config file", check.ExpectRegex, check.ID, err)
+		}
+	}
+	return nil
+}
+
+// validateMailloop checks mandatory mailloop fields and mailbox url.
+func validateMailloop(check shared.CheckConfig) error {
+	if _, _, err := net.SplitHostPort(check.Check); err != nil {
+		return fmt.Errorf("config.validate: 'check' for mailloop %q check has to be smtp server with port, fix config file", check.ID)
+	}
+	if check.MailFrom == "" || check.MailTo == "" || check.Mailbox == "" || check.MailboxUser == "" {
+		return fmt.Errorf("config.validate: 'from', 'to', 'mailbox' and 'mailbox_user' are mandatory for mailloop %q check, fix config file", check.ID)
+	}
+	u, err := url.Parse(check.Mailbox)
+	if err != nil || !found(u.Scheme, mailboxSchemes) {
+		return fmt.Errorf("config.validate: wrong 'mailbox' %q for %q check, supported schemes: %s, fix config file", check.Mailbox, check.ID, strings.Join(mailboxSchemes, ", "))
+	}
+	return nil
+}
+
+// validateSSH checks ssh credentials and thresholds.
+func validateSSH(check shared.CheckConfig) error {
+	if check.AuthUser == "" || (check.SSHKey == "" && check.AuthPass == "") {
+		return fmt.Errorf("config.validate: 'auth_user' and 'ssh_key' or 'auth_pass' are mandatory for ssh %q check, fix config file", check.ID)
+	}
+	return validateThresholds(check, shared.SSHMetrics)
+}
+
+// validateProcess checks cmdline regex, count range and thresholds.
+func validateProcess(check shared.CheckConfig) error {
+	if _, err := regexp.Compile(check.Cmdline); err != nil {
+		return fmt.Errorf("config.validate: wrong 'cmdline' %q for %q check, err: %v. fix config file", check.Cmdline, check.ID, err)
+	}
+	min := CheckProcessMinCount
+	if check.MinCount != nil {
+		min = *check.MinCount
+	}
+	if min < 0 || (check.MaxCount != nil && *check.MaxCount < min) {
+		return fmt.Errorf("config.validate: wrong 'min_count', 'max_count' range for %q check, fix config file", check.ID)
+	}
+	return validateThresholds(check, shared.ProcessMetrics)
+}
+
+// validateFile checks glob pattern and checksum algorithm.
+func validateFile(check shared.CheckConfig) error {
+	if _, err := filepath.Match(check.Check, ""); err != nil {
+		return fmt.Errorf("config.validate: wrong 'check' pattern %q for %q check, err: %v. fix config file", check.Check, check.ID, err)
+	}
+	if check.Checksum != "" {
+		algo := strings.ToLower(strings.SplitN(check.Checksum, ":", 2)[0])
+		if !found(algo, shared.FileHashes) {
+			return fmt.Errorf("config.validate: unknown 'checksum' algorithm %q for %q check, available: %s, fix config file", algo, check.ID, strings.Join(shared.FileHashes, ", "))
+		}
+	}
+	return nil
+}
+
+// validateThresholds checks if warn_at, fail_at, warn_below and fail_below
+// contain only known metrics.
+func validateThresholds(check shared.CheckConfig, known []string) error {
+	for _, t := range []struct {
+		name       string
+		thresholds map[string]float64
+	}{
+		{"warn_at", check.WarnAt},
+		{"fail_at", check.FailAt},
+		{"warn_below", check.WarnBelow},
+		{"fail_below", check.FailBelow},
+	} {
+		if err := shared.ValidateMetrics(t.thresholds, known); err != nil {
+			return fmt.Errorf("config.validate: wrong '%s' for %q check, err: %v. fix config file", t.name, check.ID, err)
+		}
+	}
+	return nil
+}
+
+// validateProxy checks proxy url, empty is ok.
+func validateProxy(p string) error {
+	if p == "" {
+		return nil
+	}
+	u, err := url.Parse(p)
+	if err != nil {
+		return err
+	}
+	if !found(u.Scheme, proxySchemes) || u.Host == "" {
+		return fmt.Errorf("proxy has to be in form scheme://host:port, supported schemes: %s", strings.Join(proxySchemes, ", "))
+	}
+	return nil
+}
+
+// validateSteps checks scenario steps.
+func validateSteps(check shared.CheckConfig) error {
+	if len(check.Steps) == 0 {
+		return fmt.Errorf("config.validate: no 'step' defined for %q scenario check, fix config file", check.ID)
+	}
+	for i, step := range check.Steps {
+		i++ // count from 1
+		if step.URL == "" {
+			return fmt.Errorf("config.validate: empty 'url' in %d. step of %q check. This field is mandatory, fix config file", i, check.ID)
+		}
+		for _, e := range step.Extract {
+			if e.Var == "" {
+				return fmt.Errorf("config.validate: empty 'var' in extract of %d. step of %q check, fix config file", i, check.ID)
+			}
+			if e.JSON == "" && e.Header == "" && e.Regex == "" {
+				return fmt.Errorf("config.validate: one of 'json', 'header', 'regex' has to be set in extract %q of %d. step of %q check, fix config file", e.Var, i, check.ID)
+			}
+			if _, err := regexp.Compile(e.Regex); err != nil {
+				return fmt.Errorf("config.validate: wrong 'regex' in extract %q of %d. step of %q check, err: %v. fix config file", e.Var, i, check.ID, err)
+			}
+			if e.JSON != "" {
+				if err := jsonpath.ValidatePath(e.JSON); err != nil {
+					return fmt.Errorf("config.validate: wrong 'json' in extract %q of %d. step of %q check, err: %v. fix config file", e.Var, i, check.ID, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 func (c *Config) validateNotifications() error {
 	for i, notif := range c.Notifications {
 		i++ //count from 1
@@ -161,6 +450,27 @@ func (c *Config) normalizeGlobal() {
 	if c.Global.PortTimeout.Duration == 0 {
 		c.Global.PortTimeout.ParseDuration(PortTimeout)
 	}
+	if c.Global.DNSTimeout.Duration == 0 {
+		c.Global.DNSTimeout.ParseDuration(DNSTimeout)
+	}
+	if c.Global.TLSTimeout.Duration == 0 {
+		c.Global.TLSTimeout.ParseDuration(TLSTimeout)
+	}
+	if c.Global.MailTimeout.Duration == 0 {
+		c.Global.MailTimeout.ParseDuration(MailTimeout)
+	}
+	if c.Global.SSHTimeout.Duration == 0 {
+		c.Global.SSHTimeout.ParseDuration(SSHTimeout)
+	}
+	if c.Global.SQLTimeout.Duration == 0 {
+		c.Global.SQLTimeout.ParseDuration(SQLTimeout)
+	}
+	if c.Global.KVTimeout.Duration == 0 {
+		c.Global.KVTimeout.ParseDuration(KVTimeout)
+	}
+	if c.Global.GRPCTimeout.Duration == 0 {
+		c.Global.GRPCTimeout.ParseDuration(GRPCTimeout)
+	}
 }
 
 func (c *Config) normalizeChecks() {
@@ -180,6 +490,9 @@ func (c *Config) normalizeChecks() {
 		}
 		if check.ExpectedTime == 0 {
 			c.Checks[i].ExpectedTime = CheckExpectedTime
+			if check.Type == "mailloop" {
+				c.Checks[i].ExpectedTime = CheckMailExpectedTime
+			}
 		}
 		if check.AllowedFails < 1 {
 			c.Checks[i].AllowedFails = CheckAllowedFails
@@ -187,6 +500,51 @@ func (c *Config) normalizeChecks() {
 		if check.AllowedSlows < 1 {
 			c.Checks[i].AllowedSlows = CheckAllowedSlows
 		}
+		if check.Type == "dns" {
+			if check.Record == "" {
+				c.Checks[i].Record = CheckDNSRecord
+			}
+			c.Checks[i].Record = strings.ToUpper(c.Checks[i].Record)
+		}
+		if check.Type == "starttls" {
+			c.Checks[i].Protocol, _ = shared.StartTLSProtocol(check.Check, check.Protocol)
+		}
+		if (check.Type == "tls" || check.Type == "starttls") && check.WarnDays == nil {
+			days := CheckTLSWarnDays
+			c.Checks[i].WarnDays = &days
+		}
+		if check.Proxy == "" {
+			c.Checks[i].Proxy = c.Global.Proxy
+		}
+		for j, step := range check.Steps {
+			if step.Method == "" {
+				c.Checks[i].Steps[j].Method = CheckMethod
+			}
+			if step.ExpectedCode == 0 {
+				c.Checks[i].Steps[j].ExpectedCode = CheckExpectedCode
+			}
+		}
+		if check.Type == "port" {
+			c.Checks[i].Protocol = strings.ToLower(check.Protocol)
+			if check.Protocol == "" {
+				c.Checks[i].Protocol = CheckPortProtocol
+			}
+		}
+		if check.Type == "process" && check.MinCount == nil {
+			min := CheckProcessMinCount
+			c.Checks[i].MinCount = &min
+		}
+		if check.Type == "mailloop" && check.Interval.Duration == 0 {
+			c.Checks[i].Interval.ParseDuration(CheckMailInterval)
+		}
+		if check.Type == "ping" {
+			if check.Count == 0 {
+				c.Checks[i].Count = CheckPingCount
+			}
+			if check.Interval.Duration == 0 {
+				c.Checks[i].Interval.ParseDuration(CheckPingInterval)
+			}
+		}
 		if check.NotifyFail == nil {
 			c.Checks[i].NotifyFail = notifids
 		}
@@ -202,6 +560,22 @@ func (c *Config) parseCheckVars() {
 	for i, check := range c.Checks {
 		c.Checks[i].Params = template.ParseEnv(check.Params, check.ID, "params")
 		c.Checks[i].Headers = template.ParseEnv(check.Headers, check.ID, "headers")
+		c.Checks[i].Proxy = template.ParseEnv(c.Checks[i].Proxy, check.ID, "proxy")
+		c.Checks[i].AuthUser = template.ParseEnv(check.AuthUser, check.ID, "auth_user")
+		c.Checks[i].AuthPass = template.ParseEnv(check.AuthPass, check.ID, "auth_pass")
+		c.Checks[i].AuthToken = template.ParseEnv(check.AuthToken, check.ID, "auth_token")
+		c.Checks[i].OAuth2TokenURL = template.ParseEnv(check.OAuth2TokenURL, check.ID, "oauth2_token_url")
+		c.Checks[i].OAuth2ClientID = template.ParseEnv(check.OAuth2ClientID, check.ID, "oauth2_client_id")
+		c.Checks[i].OAuth2ClientSecret = template.ParseEnv(check.OAuth2ClientSecret, check.ID, "oauth2_client_secret")
+		c.Checks[i].Payload = template.ParseEnv(check.Payload, check.ID, "send")
+		c.Checks[i].MailboxUser = template.ParseEnv(check.MailboxUser, check.ID, "mailbox_user")
+		c.Checks[i].MailboxPass = template.ParseEnv(check.MailboxPass, check.ID, "mailbox_pass")
+		c.Checks[i].SSHKey = template.ParseEnv(check.SSHKey, check.ID, "ssh_key")
+		c.Checks[i].DSN = template.ParseEnv(check.DSN, check.ID, "dsn")
+		for j, step := range check.Steps {
+			c.Checks[i].Steps[j].Params = template.ParseEnv(step.Params, check.ID, "step params")
+			c.Checks[i].Steps[j].Headers = template.ParseEnv(step.Headers, check.ID, "step headers")
+		}
 	}
 
 }
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+// parse decodes, validates and normalizes config from string (as main does).
+func parse(t *testing.T, s string) (*Config, error) {
+	c := &Config{}
+	if _, err := toml.Decode("[global]\nworkers = 1\n"+s, c); err != nil {
+		t.Fatalf("can not decode config: %v", err)
+	}
+	err := c.Validate()
+	c.Normalize()
+	return c, err
+}
+
+func TestValidate_assert(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   string
+		wantErr bool
+	}{
+		{"valid", `assert = ['$.status == "ok"', '$.items.length() > 0']`, false},
+		{"missing value", `assert = ['$.x ==']`, true},
+		{"not json value", `assert = ['$.status == ok']`, true},
+		{"wrong path", `assert = ['status == "ok"']`, true},
+		{"sql", "type = \"sql\"\ndriver = \"sqlite3\"\ndsn = \":memory:\"\nquery = \"select 1\"\nassert = ['$.value >']", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(t, "[[check]]\nid = \"api\"\ncheck = \"https://example.com\"\n"+tt.check+"\n")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_extract(t *testing.T) {
+	check := `
+[[check]]
+id = "login"
+type = "scenario"
+check = "login flow"
+[[check.step]]
+url = "https://example.com/login"
+[[check.step.extract]]
+var = "token"
+json = "%s"
+`
+	if _, err := parse(t, fmt.Sprintf(check, "$.token")); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+	if _, err := parse(t, fmt.Sprintf(check, "$.items[x]")); err == nil {
+		t.Errorf("Validate() wrong json path, want error")
+	}
+}
+
+func TestNormalize_processCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		counts  string
+		wantMin int
+		wantMax *int
+		wantErr bool
+	}{
+		{"default", "", 1, nil, false},
+		{"explicit zero min", "min_count = 0\nmax_count = 5", 0, intp(5), false},
+		{"must not run", "min_count = 0\nmax_count = 0", 0, intp(0), false},
+		{"max only", "max_count = 3", 1, intp(3), false},
+		{"max under default min", "max_count = 0", 0, nil, true},
+		{"max under min", "min_count = 4\nmax_count = 2", 0, nil, true},
+		{"negative", "min_count = -1", 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parse(t, "[[check]]\nid = \"nginx\"\ntype = \"process\"\ncheck = \"nginx\"\n"+tt.counts+"\n")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			check := c.Checks[0]
+			if check.MinCount == nil || *check.MinCount != tt.wantMin {
+				t.Errorf("Normalize() min_count = %v, want %d", check.MinCount, tt.wantMin)
+			}
+			if (check.MaxCount == nil) != (tt.wantMax == nil) || (check.MaxCount != nil && *check.MaxCount != *tt.wantMax) {
+				t.Errorf("Normalize() max_count = %v, want %v", check.MaxCount, tt.wantMax)
+			}
+		})
+	}
+}
+
+func intp(i int) *int {
+	return &i
+}
+
+func TestNormalize_warnDays(t *testing.T) {
+	tests := []struct {
+		name    string
+		days    string
+		want    int
+		wantErr bool
+	}{
+		{"default", "", CheckTLSWarnDays, false},
+		{"disabled", "warn_days = 0", 0, false},
+		{"explicit", "warn_days = 30", 30, false},
+		{"negative", "warn_days = -1", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parse(t, "[[check]]\nid = \"cert\"\ntype = \"tls\"\ncheck = \"example.com:443\"\n"+tt.days+"\n")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := c.Checks[0].WarnDays; got == nil || *got != tt.want {
+				t.Errorf("Normalize() warn_days = %v, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize_mailloopTime(t *testing.T) {
+	check := "[[check]]\nid = \"mail\"\ntype = \"mailloop\"\ncheck = \"smtp.example.com:587\"\nfrom = \"a@example.com\"\nto = \"b@example.com\"\nmailbox = \"imaps://imap.example.com\"\nmailbox_user = \"b\"\n"
+	tests := []struct {
+		name  string
+		check string
+		want  int64
+	}{
+		{"mailloop default", check, CheckMailExpectedTime},
+		{"mailloop explicit", check + "time = 5000\n", 5000},
+		{"web default", "[[check]]\nid = \"web\"\ncheck = \"https://example.com\"\n", CheckExpectedTime},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parse(t, tt.check)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got := c.Checks[0].ExpectedTime; got != tt.want {
+				t.Errorf("Normalize() time = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate_thresholds(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds string
+		wantErr    bool
+	}{
+		{"upper bounds", "warn_at = {load1 = 4}\nfail_at = {mem_used = 95}", false},
+		{"lower bounds", "warn_below = {load1_core = 0.1}\nfail_below = {\"disk_used:/\" = 1}", false},
+		{"unknown warn_below", "warn_below = {uptime = 600}", true},
+		{"unknown fail_below", "fail_below = {load2 = 1}", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(t, "[[check]]\nid = \"local\"\ntype = \"system\"\ncheck = \"localhost\"\n"+tt.thresholds+"\n")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_regex(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   string
+		wantErr bool
+	}{
+		{"valid", "look_for_regex = 'version: (?P<ver>\\S+)'\nmust_not_match = 'error: .*'\nmust_not_contain = 'panic'", false},
+		{"bad look_for_regex", "look_for_regex = 'version: ('", true},
+		{"bad must_not_match", "must_not_match = '[a-'", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(t, "[[check]]\nid = \"api\"\ncheck = \"https://example.com\"\n"+tt.check+"\n")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_proxyPins(t *testing.T) {
+	tests := []struct {
+		name    string
+		global  string
+		check   string
+		wantErr bool
+	}{
+		{"resolve without proxy", "", "resolve = [\"example.com:443:10.0.0.1\"]", false},
+		{"resolve with proxy", "", "proxy = \"http://proxy:3128\"\nresolve = [\"example.com:443:10.0.0.1\"]", true},
+		{"fan_out with global proxy", "proxy = \"http://proxy:3128\"\n", "fan_out = true", true},
+		{"fan_out with no_proxy", "proxy = \"http://proxy:3128\"\n", "fan_out = true\nno_proxy = true", false},
+		{"port with proxy", "", "type = \"port\"\nproxy = \"socks5://proxy:1080\"\nresolve = [\"example.com:443:10.0.0.1\"]", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			s := "[global]\nworkers = 1\n" + tt.global + "[[check]]\nid = \"web\"\ncheck = \"https://example.com\"\n" + tt.check + "\n"
+			if _, err := toml.Decode(s, c); err != nil {
+				t.Fatalf("can not decode config: %v", err)
+			}
+			err := c.Validate()
+			if (err != nil) != tt.wantErr || (err != nil && !strings.Contains(err.Error(), "proxy")) {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
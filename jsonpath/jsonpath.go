@@ -0,0 +1,343 @@
+// Package jsonpath evaluates simple JSONPath assertions against JSON documents.
+//
+// Supported assertion syntax:
+//
+//	$.status == "ok"
+//	$.items.length() > 0
+//	$.db.latency_ms < 200
+//	$.items[0].name != "x"
+//	$["some key"].enabled == true
+//	$.token                          (path has to exist and must not be null)
+//
+// Operators: ==, !=, <, <=, >, >=. Right side is JSON value (string, number, bool, null).
+// length() returns length of array, object or string.
+// Integers are compared exactly (also above 2^53), other numbers as floats.
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var operators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// AssertAll evaluates all assertions against JSON body.
+// Returns error for first failed assertion.
+func AssertAll(body string, assertions []string) error {
+	doc, err := Decode(body)
+	if err != nil {
+		return fmt.Errorf("response is not valid json, err: %v", err)
+	}
+	for _, a := range assertions {
+		if err := Assert(doc, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Assert evaluates one assertion against decoded JSON document.
+// Error names assertion and value, which was found.
+func Assert(doc interface{}, assertion string) error {
+	path, op, expected, err := parseAssertion(assertion)
+	if err != nil {
+		return fmt.Errorf("assertion `%s`: %v", assertion, err)
+	}
+
+	got, err := Lookup(doc, path)
+	if err != nil {
+		return fmt.Errorf("assertion `%s` failed: %v", assertion, err)
+	}
+
+	if op == "" {
+		if got == nil {
+			return fmt.Errorf("assertion `%s` failed, got: null", assertion)
+		}
+		return nil
+	}
+
+	ok, err := compare(got, op, expected)
+	if err != nil {
+		return fmt.Errorf("assertion `%s` failed: %v, got: %s", assertion, err, format(got))
+	}
+	if !ok {
+		return fmt.Errorf("assertion `%s` failed, got: %s", assertion, format(got))
+	}
+	return nil
+}
+
+// Lookup returns value on given path (f.e.: "$.items[0].name").
+func Lookup(doc interface{}, path string) (interface{}, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	cur := doc
+	for _, seg := range segments {
+		switch {
+		case seg.length:
+			switch v := cur.(type) {
+			case []interface{}:
+				return float64(len(v)), nil
+			case map[string]interface{}:
+				return float64(len(v)), nil
+			case string:
+				return float64(len(v)), nil
+			default:
+				return nil, fmt.Errorf("length() of %s in path %q", format(cur), path)
+			}
+		case seg.isIndex:
+			idx := seg.index
+			arr, ok := cur.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("path %q: [%d] is not an array", path, idx)
+			}
+			if idx < 0 {
+				idx = len(arr) + idx
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil, fmt.Errorf("path %q not found, array length: %d", path, len(arr))
+			}
+			cur = arr[idx]
+		default:
+			obj, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("path %q: %q is not an object", path, seg.key)
+			}
+			if cur, ok = obj[seg.key]; !ok {
+				return nil, fmt.Errorf("path %q not found", path)
+			}
+		}
+	}
+	return cur, nil
+}
+
+// Validate checks assertion syntax (path, operator and value),
+// so wrong assertion can be found before it is evaluated.
+func Validate(assertion string) error {
+	path, _, _, err := parseAssertion(assertion)
+	if err == nil {
+		_, err = parsePath(path)
+	}
+	if err != nil {
+		return fmt.Errorf("assertion `%s`: %v", assertion, err)
+	}
+	return nil
+}
+
+// ValidatePath checks path syntax (f.e.: "$.items[0].name").
+func ValidatePath(path string) error {
+	_, err := parsePath(path)
+	return err
+}
+
+// segment is one step of path: object key, array index or length().
+type segment struct {
+	key     string
+	index   int
+	isIndex bool
+	length  bool
+}
+
+// parsePath splits path to segments.
+func parsePath(path string) ([]segment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path %q must start with $", path)
+	}
+	segments := []segment{}
+	rest := path[1:]
+	for len(rest) > 0 {
+		switch {
+		case strings.HasPrefix(rest, ".length()"):
+			if len(rest) != len(".length()") {
+				return nil, fmt.Errorf("length() must be last in path %q", path)
+			}
+			return append(segments, segment{length: true}), nil
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end == -1 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("path %q: empty key", path)
+			}
+			rest = rest[end+1:]
+			segments = append(segments, segment{key: key})
+		case rest[0] == '[':
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("path %q: missing ]", path)
+			}
+			sel := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			if key, err := strconv.Unquote(sel); err == nil {
+				segments = append(segments, segment{key: key})
+				continue
+			}
+			idx, err := strconv.Atoi(sel)
+			if err != nil {
+				return nil, fmt.Errorf("path %q: wrong index %q", path, sel)
+			}
+			segments = append(segments, segment{index: idx, isIndex: true})
+		default:
+			return nil, fmt.Errorf("path %q: unexpected %q", path, rest)
+		}
+	}
+	return segments, nil
+}
+
+// parseAssertion splits assertion to path, operator and expected value.
+// Operator and value are empty for existence assertion.
+func parseAssertion(a string) (string, string, interface{}, error) {
+	inQuotes := false
+	for i := 0; i < len(a); i++ {
+		switch {
+		case a[i] == '"' && (i == 0 || a[i-1] != '\\'):
+			inQuotes = !inQuotes
+		case inQuotes:
+		default:
+			for _, op := range operators {
+				if strings.HasPrefix(a[i:], op) {
+					path := strings.TrimSpace(a[:i])
+					raw := strings.TrimSpace(a[i+len(op):])
+					expected, err := Decode(raw)
+					if err != nil {
+						return "", "", nil, fmt.Errorf("value %s is not valid json value", raw)
+					}
+					return path, op, expected, nil
+				}
+			}
+		}
+	}
+	return strings.TrimSpace(a), "", nil, nil
+}
+
+// Decode decodes JSON value, numbers are kept as json.Number,
+// so big integers do not lose precision.
+func Decode(s string) (interface{}, error) {
+	var v interface{}
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if d.More() {
+		return nil, fmt.Errorf("unexpected data after json value")
+	}
+	return v, nil
+}
+
+// number returns v as json.Number, if v is number.
+func number(v interface{}) (json.Number, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		return n, true
+	case float64:
+		return json.Number(strconv.FormatFloat(n, 'g', -1, 64)), true
+	}
+	return "", false
+}
+
+// compareNumbers returns -1, 0 or 1. Integers are compared exactly.
+func compareNumbers(a, b json.Number) int {
+	if ai, err := a.Int64(); err == nil {
+		if bi, err := b.Int64(); err == nil {
+			switch {
+			case ai < bi:
+				return -1
+			case ai > bi:
+				return 1
+			}
+			return 0
+		}
+	}
+	af, _ := a.Float64()
+	bf, _ := b.Float64()
+	switch {
+	case af < bf:
+		return -1
+	case af > bf:
+		return 1
+	}
+	return 0
+}
+
+// plain returns v with numbers converted to float64, so values
+// with numbers inside (arrays, objects) can be compared deeply.
+func plain(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var p interface{}
+	if err := json.NewDecoder(bytes.NewReader(b)).Decode(&p); err != nil {
+		return v
+	}
+	return p
+}
+
+// compare compares got and expected values using op.
+// Only numbers and strings can be ordered.
+func compare(got interface{}, op string, expected interface{}) (bool, error) {
+	g, gNum := number(got)
+	e, eNum := number(expected)
+	if gNum && eNum {
+		c := compareNumbers(g, e)
+		switch op {
+		case "==":
+			return c == 0, nil
+		case "!=":
+			return c != 0, nil
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		case ">=":
+			return c >= 0, nil
+		}
+	}
+
+	switch op {
+	case "==":
+		return reflect.DeepEqual(plain(got), plain(expected)), nil
+	case "!=":
+		return !reflect.DeepEqual(plain(got), plain(expected)), nil
+	}
+
+	switch g := got.(type) {
+	case float64, json.Number:
+		return false, fmt.Errorf("can not compare number with %s", format(expected))
+	case string:
+		e, ok := expected.(string)
+		if !ok {
+			return false, fmt.Errorf("can not compare string with %s", format(expected))
+		}
+		switch op {
+		case "<":
+			return g < e, nil
+		case "<=":
+			return g <= e, nil
+		case ">":
+			return g > e, nil
+		case ">=":
+			return g >= e, nil
+		}
+	}
+	return false, fmt.Errorf("operator %s can not be used for this value", op)
+}
+
+// format returns JSON representation of value.
+func format(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
@@ -0,0 +1,102 @@
+package jsonpath
+
+import (
+	"testing"
+)
+
+var body = `{"status": "ok", "items": [{"name": "a"}, {"name": "b"}], "db": {"latency_ms": 120}, "errors": [{"status": "ok"}], "some key": {"enabled": true}, "token": null}`
+
+func TestAssertAll(t *testing.T) {
+	tests := []struct {
+		name       string
+		assertions []string
+		wantErr    bool
+	}{
+		{"string equal", []string{`$.status == "ok"`}, false},
+		{"string not equal", []string{`$.status != "ok"`}, true},
+		{"length", []string{`$.items.length() > 0`}, false},
+		{"length fails", []string{`$.items.length() >= 3`}, true},
+		{"number", []string{`$.db.latency_ms < 200`}, false},
+		{"number fails", []string{`$.db.latency_ms < 100`}, true},
+		{"index", []string{`$.items[1].name == "b"`, `$.items[-1].name == "b"`}, false},
+		{"index out of range", []string{`$.items[2].name == "b"`}, true},
+		{"quoted key", []string{`$["some key"].enabled == true`}, false},
+		{"exists", []string{`$.db`}, false},
+		{"null", []string{`$.token`}, true},
+		{"missing", []string{`$.nothing == 1`}, true},
+		{"wrong type", []string{`$.status > 1`}, true},
+		{"wrong value", []string{`$.status == ok`}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := AssertAll(body, tt.assertions); (err != nil) != tt.wantErr {
+				t.Errorf("AssertAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertAll_notJSON(t *testing.T) {
+	if err := AssertAll("<html></html>", []string{`$.status == "ok"`}); err == nil {
+		t.Errorf("AssertAll() expected error for non json body")
+	}
+}
+
+func TestAssert_errorText(t *testing.T) {
+	err := AssertAll(body, []string{`$.status == "error"`})
+	want := "assertion `$.status == \"error\"` failed, got: \"ok\""
+	if err == nil || err.Error() != want {
+		t.Errorf("Assert() error = %v, want %v", err, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		assertion string
+		wantErr   bool
+	}{
+		{`$.status == "ok"`, false},
+		{`$.items.length() > 0`, false},
+		{`$["some key"].enabled == true`, false},
+		{`$.items[-1].name`, false},
+		{`$.x ==`, true},
+		{`$.status == ok`, true},
+		{`status == "ok"`, true},
+		{`$.items[a] == 1`, true},
+		{`$.items[0 == 1`, true},
+		{`$.items.length().x > 0`, true},
+		{`$..status`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.assertion, func(t *testing.T) {
+			if err := Validate(tt.assertion); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertAll_numbers(t *testing.T) {
+	body := `{"id": 9007199254740993, "lag": 1.5, "count": 3, "list": [1, 2]}`
+	tests := []struct {
+		name      string
+		assertion string
+		wantErr   bool
+	}{
+		{"big int equal", `$.id == 9007199254740993`, false},
+		{"big int differs", `$.id == 9007199254740992`, true},
+		{"big int greater", `$.id > 9007199254740992`, false},
+		{"float", `$.lag < 2`, false},
+		{"int equals float", `$.count == 3.0`, false},
+		{"array", `$.list == [1, 2]`, false},
+		{"length", `$.list.length() == 2`, false},
+		{"number with string", `$.count < "4"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := AssertAll(body, []string{tt.assertion}); (err != nil) != tt.wantErr {
+				t.Errorf("AssertAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -58,6 +58,13 @@ func main() {
 		HTTPTimeout: c.Global.HTTPTimeout,
 		PingTimeout: c.Global.PingTimeout,
 		PortTimeout: c.Global.PortTimeout,
+		DNSTimeout:  c.Global.DNSTimeout,
+		TLSTimeout:  c.Global.TLSTimeout,
+		MailTimeout: c.Global.MailTimeout,
+		SSHTimeout:  c.Global.SSHTimeout,
+		SQLTimeout:  c.Global.SQLTimeout,
+		KVTimeout:   c.Global.KVTimeout,
+		GRPCTimeout: c.Global.GRPCTimeout,
 	}
 
 	chm := check.NewManager(cc)
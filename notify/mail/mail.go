@@ -53,7 +53,7 @@ func (m *Mail) Send(c shared.CheckConfig, n shared.NotifConfig) error {
 	}
 
 	message += "\r\n" + base64.StdEncoding.EncodeToString([]byte(n.Text))
-	err := sendMail(n.Server, n.Port, auth, n.IgnoreCert, n.From, n.To, []byte(message))
+	err := SendMail(n.Server, n.Port, auth, n.IgnoreCert, n.From, n.To, []byte(message))
 
 	p := "" // just for logging
 	if len(n.Pass) >= 4 {
@@ -87,7 +87,7 @@ func encodeRFC2047(s string) string {
 	return strings.Trim(addr.String(), " <@>")
 }
 
-// sendMail connects to the server at addr, switches to TLS if
+// SendMail connects to the server at addr, switches to TLS if
 // possible, authenticates with the optional mechanism a if possible,
 // and then sends an email from address from, to addresses to, with
 // message msg.
@@ -108,14 +108,14 @@ func encodeRFC2047(s string) string {
 // functionality. Higher-level packages exist outside of the standard
 // library.
 //
-// sendMail ripped from net/smtp package, added ability to send mails
-// via TLS (port: 465).
+// SendMail ripped from net/smtp package, added ability to send mails
+// via TLS (port: 465). It is exported for mailloop check.
 //
 // Changes for zoriX:
 //  - fixed potential MITM atack
 //  - added option to ignore certificate
 //  - fixed stuck connection if server has port closed (added timeout)
-func sendMail(host string, port int, a smtp.Auth, ignoreCert bool, from string, to []string, msg []byte) error {
+func SendMail(host string, port int, a smtp.Auth, ignoreCert bool, from string, to []string, msg []byte) error {
 	if err := validateLine(from); err != nil {
 		return err
 	}
@@ -0,0 +1,97 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+const (
+	// diffMaxLines is max amount of removed and added lines in diff snippet.
+	diffMaxLines = 10
+	// diffMaxLineLen is max line length in diff snippet.
+	diffMaxLineLen = 200
+)
+
+// content keeps last known response content for change detection.
+type content struct {
+	hash string
+	body string
+}
+
+// detectChange hashes response (regions matching ChangeIgnore are removed)
+// and compares it with last known content. If it differs, check fails
+// and {content_diff} var contains diff snippet.
+// Known content is updated only if check did not fail for other reason,
+// so error pages are not taken as new content.
+func (p *Processor) detectChange(r *shared.CheckConfig) {
+	body := r.Response
+	for _, expr := range r.ChangeIgnore {
+		body = p.regexp(expr).ReplaceAllString(body, "")
+	}
+	sum := sha256.Sum256([]byte(body))
+	hash := hex.EncodeToString(sum[:])
+
+	if r.Vars == nil {
+		r.Vars = map[string]string{}
+	}
+	r.Vars["content_hash"] = hash
+
+	if r.Fails > 0 {
+		return
+	}
+
+	prev, ok := p.contents[r.ID]
+	p.contents[r.ID] = content{hash: hash, body: body}
+	if !ok || prev.hash == hash {
+		return
+	}
+
+	r.Fails = 1
+	r.Vars["content_diff"] = diffSnippet(prev.body, body)
+	if r.Error == nil || shared.IsWarning(r.Error) {
+		r.Error = fmt.Errorf("content changed, hash: %s, previous: %s", hash[:12], prev.hash[:12])
+	}
+}
+
+// diffSnippet returns changed lines of old and new text (common leading
+// and trailing lines are skipped), removed lines are prefixed by "- ",
+// added by "+ ".
+func diffSnippet(old, new string) string {
+	ol := strings.Split(old, "\n")
+	nl := strings.Split(new, "\n")
+
+	start := 0
+	for start < len(ol) && start < len(nl) && ol[start] == nl[start] {
+		start++
+	}
+	oe, ne := len(ol), len(nl)
+	for oe > start && ne > start && ol[oe-1] == nl[ne-1] {
+		oe--
+		ne--
+	}
+
+	lines := []string{fmt.Sprintf("@@ line %d @@", start+1)}
+	lines = append(lines, diffLines("- ", ol[start:oe])...)
+	lines = append(lines, diffLines("+ ", nl[start:ne])...)
+	return strings.Join(lines, "\n")
+}
+
+// diffLines prefixes lines, it shortens too long lines and too many lines.
+func diffLines(prefix string, ls []string) []string {
+	res := []string{}
+	for i, l := range ls {
+		if i == diffMaxLines {
+			res = append(res, fmt.Sprintf("%s... (%d more lines)", prefix, len(ls)-diffMaxLines))
+			break
+		}
+		if len(l) > diffMaxLineLen {
+			l = l[:diffMaxLineLen] + "..."
+		}
+		res = append(res, prefix+l)
+	}
+	return res
+}
@@ -0,0 +1,51 @@
+package processor
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+func Test_diffSnippet(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{"changed line", "a\nb\nc", "a\nx\nc", "@@ line 2 @@\n- b\n+ x"},
+		{"added line", "a\nc", "a\nb\nc", "@@ line 2 @@\n+ b"},
+		{"removed line", "a\nb\nc", "a\nc", "@@ line 2 @@\n- b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffSnippet(tt.old, tt.new); got != tt.want {
+				t.Errorf("diffSnippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessor_detectChange(t *testing.T) {
+	p := &Processor{regexps: map[string]*regexp.Regexp{}, contents: map[string]content{}}
+	c := shared.CheckConfig{ID: "page", DetectChange: true, ChangeIgnore: []string{`time: \d+`}}
+
+	runs := []struct {
+		response  string
+		wantFails int
+	}{
+		{"hello\ntime: 1", 0},
+		{"hello\ntime: 2", 0},
+		{"hacked\ntime: 3", 1},
+		{"hacked\ntime: 4", 0},
+	}
+	for i, run := range runs {
+		r := c
+		r.Response = run.response
+		p.detectChange(&r)
+		if r.Fails != run.wantFails {
+			t.Errorf("run %d: detectChange() fails = %d, want %d", i+1, r.Fails, run.wantFails)
+		}
+	}
+}
@@ -5,10 +5,12 @@ package processor
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/ernierasta/zorix/jsonpath"
 	"github.com/ernierasta/zorix/shared"
 	log "github.com/sirupsen/logrus"
 )
@@ -21,6 +23,8 @@ type Processor struct {
 	recoveryChans map[string]chan bool
 	checks        map[string]*shared.CheckConfig
 	notifications map[string]*shared.NotifConfig
+	regexps       map[string]*regexp.Regexp
+	contents      map[string]content
 	mutex         *sync.Mutex
 }
 
@@ -37,6 +41,8 @@ func New(resultChan chan shared.CheckConfig, notifChan chan shared.NotifiedCheck
 		recoveryChans: make(map[string]chan bool, checkAmmount*len(notifications)),
 		checks:        make(map[string]*shared.CheckConfig, checkAmmount),
 		notifications: notes,
+		regexps:       make(map[string]*regexp.Regexp),
+		contents:      make(map[string]content, checkAmmount),
 		mutex:         &sync.Mutex{},
 	}
 }
@@ -63,11 +69,15 @@ func (p *Processor) Listen() {
 func (p *Processor) analyze(r shared.CheckConfig) shared.CheckConfig {
 
 	if r.Error != nil {
-		r.Fails = 1
+		if shared.IsWarning(r.Error) {
+			r.Slowdowns = 1
+		} else {
+			r.Fails = 1
+		}
 	}
 	if r.ReturnedCode != r.ExpectedCode {
 		r.Fails = 1
-		if r.Error == nil {
+		if r.Error == nil || shared.IsWarning(r.Error) {
 			r.Error = fmt.Errorf("wrong response code")
 		}
 	}
@@ -77,6 +87,43 @@ func (p *Processor) analyze(r shared.CheckConfig) shared.CheckConfig {
 			r.Error = fmt.Errorf("response does not contain: %s", r.LookFor)
 		}
 	}
+	if r.LookForRegex != "" {
+		if m := p.regexp(r.LookForRegex).FindStringSubmatch(r.Response); m != nil {
+			r.Vars = addMatchVars(r.Vars, "match", p.regexp(r.LookForRegex), m)
+		} else {
+			r.Fails = 1
+			if r.Error == nil || shared.IsWarning(r.Error) {
+				r.Error = fmt.Errorf("response does not match: %s", r.LookForRegex)
+			}
+		}
+	}
+	if r.MustNotContain != "" && strings.Contains(r.Response, r.MustNotContain) {
+		r.Fails = 1
+		if r.Error == nil || shared.IsWarning(r.Error) {
+			r.Error = fmt.Errorf("response contains: %s", r.MustNotContain)
+		}
+	}
+	if r.MustNotMatch != "" {
+		if m := p.regexp(r.MustNotMatch).FindStringSubmatch(r.Response); m != nil {
+			r.Vars = addMatchVars(r.Vars, "unwanted", p.regexp(r.MustNotMatch), m)
+			r.Fails = 1
+			if r.Error == nil || shared.IsWarning(r.Error) {
+				r.Error = fmt.Errorf("response matches: %s, found: %s", r.MustNotMatch, m[0])
+			}
+		}
+	}
+	if len(r.Assertions) > 0 {
+		if err := jsonpath.AssertAll(r.Response, r.Assertions); err != nil {
+			r.Fails = 1
+			if r.Error == nil || shared.IsWarning(r.Error) {
+				r.Error = err
+			}
+		}
+	}
+
+	if r.DetectChange {
+		p.detectChange(&r)
+	}
 
 	if r.ReturnedTime > r.ExpectedTime {
 		r.Slowdowns = 1
@@ -89,6 +136,39 @@ func (p *Processor) analyze(r shared.CheckConfig) shared.CheckConfig {
 
 }
 
+// regexp returns compiled regular expression, compiled expressions are cached.
+// Expressions are validated in config, so compile error should not happen.
+func (p *Processor) regexp(expr string) *regexp.Regexp {
+	if re, ok := p.regexps[expr]; ok {
+		return re
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		log.Errorf("processor.regexp: can not compile %q, err: %v", expr, err)
+		re = regexp.MustCompile(`[^\s\S]`) // never matches
+	}
+	p.regexps[expr] = re
+	return re
+}
+
+// addMatchVars adds regexp match and capture groups to vars.
+// Whole match is stored as prefix, groups as prefix_1, prefix_2, ...
+// and named groups also as prefix_name.
+func addMatchVars(vars map[string]string, prefix string, re *regexp.Regexp, m []string) map[string]string {
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	vars[prefix] = m[0]
+	names := re.SubexpNames()
+	for i := 1; i < len(m); i++ {
+		vars[fmt.Sprintf("%s_%d", prefix, i)] = m[i]
+		if names[i] != "" {
+			vars[prefix+"_"+names[i]] = m[i]
+		}
+	}
+	return vars
+}
+
 // updateCheckResult will store actual result in checks map.
 // It will increment fail or slowdown counter if needed.
 func (p *Processor) updateCheckResult(r shared.CheckConfig) {
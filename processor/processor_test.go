@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ernierasta/zorix/shared"
+	"github.com/ernierasta/zorix/template"
+)
+
+func TestProcessor_analyze_match(t *testing.T) {
+	response := "status: degraded\nversion: 1.4.2\nerror: disk full"
+	tests := []struct {
+		name      string
+		c         shared.CheckConfig
+		wantFails int
+		wantErr   string
+		wantVars  map[string]string
+	}{
+		{"look_for_regex match", shared.CheckConfig{LookForRegex: `version: (?P<ver>\d+\.\d+)\.(\d+)`}, 0, "",
+			map[string]string{"match": "version: 1.4.2", "match_1": "1.4", "match_ver": "1.4", "match_2": "2"}},
+		{"look_for_regex no match", shared.CheckConfig{LookForRegex: `status: ok`}, 1, "response does not match: status: ok", nil},
+		{"must_not_contain ok", shared.CheckConfig{MustNotContain: "panic"}, 0, "", nil},
+		{"must_not_contain found", shared.CheckConfig{MustNotContain: "degraded"}, 1, "response contains: degraded", nil},
+		{"must_not_match ok", shared.CheckConfig{MustNotMatch: `fatal: .*`}, 0, "", nil},
+		{"must_not_match found", shared.CheckConfig{MustNotMatch: `error: (\w+ \w+)`}, 1, "response matches: error: (\\w+ \\w+), found: error: disk full",
+			map[string]string{"unwanted": "error: disk full", "unwanted_1": "disk full"}},
+		{"both", shared.CheckConfig{LookForRegex: `version: \S+`, MustNotMatch: `status: (\w+)`}, 1, "response matches: status: (\\w+), found: status: degraded",
+			map[string]string{"match": "version: 1.4.2", "unwanted": "status: degraded", "unwanted_1": "degraded"}},
+		{"warning replaced", shared.CheckConfig{MustNotContain: "disk full", ResultData: shared.ResultData{Error: shared.NewWarning("slow")}}, 1, "response contains: disk full", nil},
+	}
+	p := New(nil, nil, 1, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.c
+			c.ExpectedCode, c.ReturnedCode, c.ExpectedTime = 200, 200, 1000
+			c.Response = response
+			r := p.analyze(c)
+			if r.Fails != tt.wantFails {
+				t.Errorf("analyze() fails = %d, want %d", r.Fails, tt.wantFails)
+			}
+			if got := errText(r.Error); got != tt.wantErr {
+				t.Errorf("analyze() error = %q, want %q", got, tt.wantErr)
+			}
+			if !reflect.DeepEqual(r.Vars, tt.wantVars) {
+				t.Errorf("analyze() vars = %v, want %v", r.Vars, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestProcessor_analyze_matchTemplate(t *testing.T) {
+	p := New(nil, nil, 1, nil)
+	c := shared.CheckConfig{Check: "https://example.com", ExpectedCode: 200, ExpectedTime: 1000,
+		LookForRegex: `version: (?P<ver>\S+)`, MustNotMatch: `error: (.+)`}
+	c.ReturnedCode = 200
+	c.Response = "version: 1.4.2\nerror: disk full"
+	r := p.analyze(c)
+	got := template.Parse("{check} {match_ver}: {unwanted_1} ({error})", r, "mail", "text")
+	want := "https://example.com 1.4.2: disk full (response matches: error: (.+), found: error: disk full)"
+	if got != want {
+		t.Errorf("template.Parse() = %q, want %q", got, want)
+	}
+}
+
+func TestProcessor_regexp(t *testing.T) {
+	p := New(nil, nil, 1, nil)
+	re := p.regexp(`a+`)
+	if p.regexp(`a+`) != re {
+		t.Errorf("regexp() expression is not cached")
+	}
+	// invalid expressions are rejected by config, here they never match
+	if bad := p.regexp(`(`); bad.MatchString("(") || bad.MatchString("") {
+		t.Errorf("regexp() invalid expression matches")
+	}
+}
+
+func errText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -1,5 +1,7 @@
 package shared
 
+import "fmt"
+
 // CMConfig is used for configuring check.Manager.
 type CMConfig struct {
 	Checks      []CheckConfig
@@ -8,4 +10,34 @@ type CMConfig struct {
 	HTTPTimeout Duration
 	PingTimeout Duration
 	PortTimeout Duration
+	DNSTimeout  Duration
+	TLSTimeout  Duration
+	MailTimeout Duration
+	SSHTimeout  Duration
+	SQLTimeout  Duration
+	KVTimeout   Duration
+	GRPCTimeout Duration
+}
+
+// Warning is error returned by worker, when check passed, but some warning
+// threshold was exceeded (f.e.: certificate expires soon).
+// Processor counts it as slowdown, not as failure.
+type Warning struct {
+	msg string
+}
+
+// NewWarning returns Warning with formatted message.
+func NewWarning(format string, a ...interface{}) *Warning {
+	return &Warning{fmt.Sprintf(format, a...)}
+}
+
+// Error fulfils error interface
+func (w *Warning) Error() string {
+	return w.msg
+}
+
+// IsWarning returns true if err is Warning.
+func IsWarning(err error) bool {
+	_, ok := err.(*Warning)
+	return ok
 }
@@ -0,0 +1,103 @@
+package shared
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Option values supported by check workers. They are here (not in
+// worker packages), so config can validate them without importing
+// workers and their dependencies (f.e.: database drivers).
+
+// SQLDrivers is slice of supported sql drivers.
+// sqlite3 is available only in binary built with cgo.
+var SQLDrivers = []string{"postgres", "mysql", "sqlite3"}
+
+// SSHMetrics is list of metrics measured by ssh worker.
+var SSHMetrics = []string{"load1", "load5", "load15", "mem_used", "swap_used", "disk_used", "inode_used",
+	"uptime", "rx_bytes", "tx_bytes", "rx_rate", "tx_rate"}
+
+// SystemMetrics is list of metrics measured by system worker.
+var SystemMetrics = []string{"load1", "load5", "load15", "load1_core", "load5_core", "load15_core",
+	"mem_used", "swap_used", "disk_used", "inode_used", "open_fds", "fds_used"}
+
+// ProcessMetrics is list of metrics measured by process worker.
+var ProcessMetrics = []string{"count", "rss", "cpu_time"}
+
+// FileHashes is list of checksum algorithms supported by file worker.
+var FileHashes = []string{"md5", "sha1", "sha256", "sha512"}
+
+// StartTLSProtocols is slice of protocols supported by starttls worker.
+var StartTLSProtocols = []string{"smtp", "imap", "pop3", "xmpp", "ldap", "postgres"}
+
+// startTLSPorts maps well known ports to protocols.
+var startTLSPorts = map[string]string{
+	"25":   "smtp",
+	"587":  "smtp",
+	"143":  "imap",
+	"110":  "pop3",
+	"5222": "xmpp",
+	"389":  "ldap",
+	"5432": "postgres",
+}
+
+// StartTLSProtocol returns protocol for address. If protocol is not set,
+// it is guessed from well known port.
+func StartTLSProtocol(address, protocol string) (string, error) {
+	protocol = strings.ToLower(protocol)
+	if protocol != "" {
+		for _, p := range StartTLSProtocols {
+			if p == protocol {
+				return protocol, nil
+			}
+		}
+		return "", fmt.Errorf("unknown protocol %q, available: %s", protocol, strings.Join(StartTLSProtocols, ", "))
+	}
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("address %q has to be in form host:port", address)
+	}
+	if p, ok := startTLSPorts[port]; ok {
+		return p, nil
+	}
+	return "", fmt.Errorf("can not guess protocol for port %s, set 'protocol'", port)
+}
+
+// ParseResolve parses curl --resolve like entry "host:port:ip".
+// Returns "host:port" and ip.
+func ParseResolve(r string) (string, string, error) {
+	parts := strings.SplitN(r, ":", 3)
+	if len(parts) != 3 {
+		return "", "", fmt.Errorf("resolve %q has to be in form host:port:ip", r)
+	}
+	ip := strings.Trim(parts[2], "[]")
+	if net.ParseIP(ip) == nil {
+		return "", "", fmt.Errorf("resolve %q: %q is not ip address", r, ip)
+	}
+	return strings.ToLower(net.JoinHostPort(parts[0], parts[1])), ip, nil
+}
+
+// ValidateMetrics returns error if any threshold is not in known metrics.
+// Per mount metrics (f.e.: "disk_used:/var") are accepted too.
+func ValidateMetrics(thresholds map[string]float64, known []string) error {
+	names := make([]string, 0, len(thresholds))
+	for name := range thresholds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		found := false
+		for _, k := range known {
+			if k == name || strings.HasPrefix(name, k+":") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown metric %q, available: %s", name, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package shared
+
+import "testing"
+
+func TestStartTLSProtocol(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		protocol string
+		want     string
+		wantErr  bool
+	}{
+		{"submission", "mail.example.com:587", "", "smtp", false},
+		{"imap", "mail.example.com:143", "", "imap", false},
+		{"explicit", "mail.example.com:2525", "SMTP", "smtp", false},
+		{"unknown port", "mail.example.com:2525", "", "", true},
+		{"unknown protocol", "mail.example.com:25", "ftp", "", true},
+		{"missing port", "mail.example.com", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StartTLSProtocol(tt.address, tt.protocol)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("StartTLSProtocol() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("StartTLSProtocol() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseResolve(t *testing.T) {
+	tests := []struct {
+		name         string
+		r            string
+		wantHostPort string
+		wantIP       string
+		wantErr      bool
+	}{
+		{"ipv4", "www.Example.com:443:10.0.0.1", "www.example.com:443", "10.0.0.1", false},
+		{"ipv6", "example.com:80:[2001:db8::1]", "example.com:80", "2001:db8::1", false},
+		{"missing port", "example.com:10.0.0.1", "", "", true},
+		{"not ip", "example.com:80:backend", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostPort, ip, err := ParseResolve(tt.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseResolve() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if hostPort != tt.wantHostPort || ip != tt.wantIP {
+				t.Errorf("ParseResolve() = %v, %v, want %v, %v", hostPort, ip, tt.wantHostPort, tt.wantIP)
+			}
+		})
+	}
+}
+
+func TestValidateMetrics(t *testing.T) {
+	known := []string{"disk_used", "load1"}
+	tests := []struct {
+		name       string
+		thresholds map[string]float64
+		wantErr    bool
+	}{
+		{"known", map[string]float64{"load1": 1}, false},
+		{"per mount", map[string]float64{"disk_used:/var": 80}, false},
+		{"unknown", map[string]float64{"load2": 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateMetrics(tt.thresholds, known); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMetrics() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
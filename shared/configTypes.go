@@ -10,44 +10,189 @@ type Global struct {
 	HTTPTimeout         Duration `toml:"http_duration"`
 	PingTimeout         Duration `toml:"ping_timeout"`
 	PortTimeout         Duration `toml:"port_timeout"`
-	NotifySubjectFail   string   `toml:"notify_subject_fail"`
-	NotifySubjectSlow   string   `toml:"notify_subject_slow"`
-	NotifySubjectFailOK string   `toml:"notify_subject_fail_ok"`
-	NotifySubjectSlowOK string   `toml:"notify_subject_slow_ok"`
-	NotifyTextFail      string   `toml:"notify_text_fail"`
-	NotifyTextSlow      string   `toml:"notify_text_slow"`
-	NotifyTextFailOK    string   `toml:"notify_text_fail_ok"`
-	NotifyTextSlowOK    string   `toml:"notify_text_slow_ok"`
+	DNSTimeout          Duration `toml:"dns_timeout"`
+	TLSTimeout          Duration `toml:"tls_timeout"`
+	MailTimeout         Duration `toml:"mail_timeout"`
+	SSHTimeout          Duration `toml:"ssh_timeout"`
+	SQLTimeout          Duration `toml:"sql_timeout"`
+	KVTimeout           Duration `toml:"kv_timeout"`
+	GRPCTimeout         Duration `toml:"grpc_timeout"`
+	Proxy               string
+	NotifySubjectFail   string `toml:"notify_subject_fail"`
+	NotifySubjectSlow   string `toml:"notify_subject_slow"`
+	NotifySubjectFailOK string `toml:"notify_subject_fail_ok"`
+	NotifySubjectSlowOK string `toml:"notify_subject_slow_ok"`
+	NotifyTextFail      string `toml:"notify_text_fail"`
+	NotifyTextSlow      string `toml:"notify_text_slow"`
+	NotifyTextFailOK    string `toml:"notify_text_fail_ok"`
+	NotifyTextSlowOK    string `toml:"notify_text_slow_ok"`
 }
 
 // CheckConfig type represents all check attributes
 type CheckConfig struct {
-	ID           string
-	Type         string
-	Check        string
-	Params       string
-	Headers      string
-	Method       string
-	Redirs       int
-	Repeat       Duration
-	ExpectedCode int      `toml:"code"`
-	ExpectedTime int64    `toml:"time"`
-	LookFor      string   `toml:"look_for"`
-	AllowedFails int      `toml:"fails"`
-	AllowedSlows int      `toml:"slows"`
-	NotifyFail   []string `toml:"notify_fail"`
-	NotifySlow   []string `toml:"notify_slow"`
+	ID             string
+	Type           string
+	Check          string
+	Params         string
+	Headers        string
+	Method         string
+	Redirs         int
+	Repeat         Duration
+	ExpectedCode   int      `toml:"code"`
+	ExpectedTime   int64    `toml:"time"`
+	LookFor        string   `toml:"look_for"`
+	LookForRegex   string   `toml:"look_for_regex"`
+	MustNotContain string   `toml:"must_not_contain"`
+	MustNotMatch   string   `toml:"must_not_match"`
+	Assertions     []string `toml:"assert"`
+	AllowedFails   int      `toml:"fails"`
+	AllowedSlows   int      `toml:"slows"`
+	NotifyFail     []string `toml:"notify_fail"`
+	NotifySlow     []string `toml:"notify_slow"`
+
+	// web, port network path
+	Proxy     string
+	NoProxy   bool `toml:"no_proxy"`
+	IPVersion int  `toml:"ip_version"`
+	Source    string
+	Resolve   []string
+	FanOut    bool `toml:"fan_out"`
+
+	// web
+	ExpectedHeaders  []HeaderExpect `toml:"header"`
+	ExpectedFinalURL string         `toml:"expected_final_url"`
+
+	IgnoreCert         bool     `toml:"ignore_cert"`
+	ClientCert         string   `toml:"client_cert"`
+	ClientKey          string   `toml:"client_key"`
+	CAFile             string   `toml:"ca_file"`
+	AuthUser           string   `toml:"auth_user"`
+	AuthPass           string   `toml:"auth_pass"`
+	AuthToken          string   `toml:"auth_token"`
+	OAuth2TokenURL     string   `toml:"oauth2_token_url"`
+	OAuth2ClientID     string   `toml:"oauth2_client_id"`
+	OAuth2ClientSecret string   `toml:"oauth2_client_secret"`
+	OAuth2Scopes       []string `toml:"oauth2_scopes"`
+
+	DetectChange bool     `toml:"detect_change"`
+	ChangeIgnore []string `toml:"change_ignore"`
+
+	// web, phase thresholds in ms
+	SlowDNS      int64 `toml:"slow_dns"`
+	SlowConnect  int64 `toml:"slow_connect"`
+	SlowTLS      int64 `toml:"slow_tls"`
+	SlowTTFB     int64 `toml:"slow_ttfb"`
+	SlowTransfer int64 `toml:"slow_transfer"`
+
+	// port
+	Protocol    string
+	Payload     string `toml:"send"`
+	Expect      string
+	ExpectRegex string `toml:"expect_regex"`
+
+	// dns
+	Record         string
+	Resolver       string
+	ExpectedAnswer []string `toml:"answer"`
+	AnswerSubset   bool     `toml:"answer_subset"`
+
+	// tls
+	WarnDays *int `toml:"warn_days"` // nil means no warning
+
+	// ping
+	Count    int
+	Interval Duration
+	MaxLoss  int `toml:"max_loss"`
+
+	// mailloop
+	MailFrom      string `toml:"from"`
+	MailTo        string `toml:"to"`
+	Mailbox       string
+	MailboxUser   string `toml:"mailbox_user"`
+	MailboxPass   string `toml:"mailbox_pass"`
+	MailboxFolder string `toml:"mailbox_folder"`
+
+	// sql
+	Driver string
+	DSN    string `toml:"dsn"`
+	Query  string
+
+	// ssh
+	SSHKey     string `toml:"ssh_key"`
+	KnownHosts string `toml:"known_hosts"`
+
+	// grpc
+	Service string
+	TLS     bool `toml:"tls"`
+
+	// redis, memcached
+	ExpectInfo map[string]string `toml:"expect_info"`
+
+	// process
+	Cmdline  string
+	Pidfile  string
+	MinCount *int `toml:"min_count"` // nil means not checked
+	MaxCount *int `toml:"max_count"`
+
+	// file
+	MaxAge        Duration `toml:"max_age"`
+	MinSize       int64    `toml:"min_size"`
+	Checksum      string
+	VerifyArchive bool `toml:"verify_archive"`
+
+	// ssh, system, process, redis, memcached thresholds
+	Mounts    []string
+	WarnAt    map[string]float64 `toml:"warn_at"`
+	FailAt    map[string]float64 `toml:"fail_at"`
+	WarnBelow map[string]float64 `toml:"warn_below"` // lower bounds
+	FailBelow map[string]float64 `toml:"fail_below"`
+
+	// scenario
+	Steps []Step `toml:"step"`
+
 	ResultData
 }
 
+// HeaderExpect defines expected response header.
+// If neither Equals nor Regex is set, header has to be present.
+type HeaderExpect struct {
+	Name   string
+	Equals string
+	Regex  string
+}
+
+// Step is one request of scenario check.
+type Step struct {
+	Name         string
+	Method       string
+	URL          string
+	Headers      string
+	Params       string
+	ExpectedCode int    `toml:"code"`
+	LookFor      string `toml:"look_for"`
+	Auth         bool   // send check auth (auth_user, auth_token, oauth2) with step
+	Extract      []Extract
+}
+
+// Extract defines value taken from step response and stored as Var.
+// Only one of JSON (json path), Header or Regex should be set.
+type Extract struct {
+	Var    string
+	JSON   string `toml:"json"`
+	Header string
+	Regex  string
+}
+
 // ResultData contains Check additional data.
 // Probably not used separetly.
 type ResultData struct {
 	WorkerType      Worker
+	Address         string // resolved address in fan out mode
 	ReturnedCode    int
 	Response        string
 	Error           error
 	ReturnedTime    int64
+	Vars            map[string]string
 	Slowdowns       int
 	Fails           int
 	Timestamp       time.Time
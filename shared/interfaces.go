@@ -5,6 +5,13 @@ type Worker interface {
 	Send(c CheckConfig) (code int, respBody string, reqDuration int64, err error)
 }
 
+// VarsWorker can be fulfilled by worker, which returns additional result
+// data (f.e.: certificate expiry). Returned vars are available in templates.
+// If worker implements it, SendVars is used instead of Send.
+type VarsWorker interface {
+	SendVars(c CheckConfig) (code int, respBody string, reqDuration int64, vars map[string]string, err error)
+}
+
 // Notifier sends notification away
 type Notifier interface {
 	Send(c CheckConfig, n NotifConfig) error
@@ -36,8 +36,15 @@ func Parse(ts string, c shared.CheckConfig, nID, field string) string {
 //
 // Those are result data:
 // response_code, response_time, response, timestamp
+// and any additional result vars returned by worker (f.e.: cert_expiry).
+// Worker vars have priority over config fields with the same name
+// (f.e.: process {count} vs ping count).
 func CheckVarsParser(c shared.CheckConfig) func(w io.Writer, tag string) (int, error) {
 	return func(w io.Writer, tag string) (int, error) {
+		// additional result data returned by worker
+		if v, ok := c.Vars[tag]; ok {
+			return w.Write([]byte(v))
+		}
 		switch tag {
 		case "cID":
 			return w.Write([]byte(c.ID))
@@ -57,6 +64,43 @@ func CheckVarsParser(c shared.CheckConfig) func(w io.Writer, tag string) (int, e
 			return w.Write(spaceIfVal(c.Method))
 		case "look_for":
 			return w.Write(spaceIfVal(c.LookFor))
+		case "record":
+			return w.Write(spaceIfVal(c.Record))
+		case "resolver":
+			return w.Write(spaceIfVal(c.Resolver))
+		case "answer":
+			return w.Write([]byte(strings.Join(c.ExpectedAnswer, ", ")))
+		case "count":
+			return w.Write([]byte(strconv.Itoa(c.Count)))
+		case "interval":
+			return w.Write([]byte(c.Interval.String()))
+		case "max_loss":
+			return w.Write([]byte(strconv.Itoa(c.MaxLoss)))
+		case "look_for_regex":
+			return w.Write(spaceIfVal(c.LookForRegex))
+		case "must_not_contain":
+			return w.Write(spaceIfVal(c.MustNotContain))
+		case "must_not_match":
+			return w.Write(spaceIfVal(c.MustNotMatch))
+		case "expected_final_url":
+			return w.Write(spaceIfVal(c.ExpectedFinalURL))
+		case "assert":
+			return w.Write([]byte(strings.Join(c.Assertions, ", ")))
+		case "protocol":
+			return w.Write(spaceIfVal(c.Protocol))
+		case "expect":
+			return w.Write(spaceIfVal(c.Expect))
+		case "expect_regex":
+			return w.Write(spaceIfVal(c.ExpectRegex))
+		case "service":
+			return w.Write(spaceIfVal(c.Service))
+		case "mailbox":
+			return w.Write(spaceIfVal(c.Mailbox))
+		case "address":
+			if c.Address != "" {
+				return w.Write([]byte(" [" + c.Address + "]"))
+			}
+			return w.Write([]byte(""))
 		case "response":
 			return w.Write([]byte(c.Response))
 		case "timestamp":
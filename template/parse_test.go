@@ -0,0 +1,32 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/ernierasta/zorix/shared"
+)
+
+func TestParse(t *testing.T) {
+	process := shared.CheckConfig{ID: "nginx", Type: "process", Check: "nginx"}
+	process.ReturnedCode = 500
+	process.Vars = map[string]string{"count": "3", "pids": "10,11,12", "rss": "42"}
+	ping := shared.CheckConfig{ID: "gw", Type: "ping", Check: "10.0.0.1", Count: 5}
+	tests := []struct {
+		name string
+		ts   string
+		c    shared.CheckConfig
+		want string
+	}{
+		{"process vars", "{check}: {count} processes ({pids}), rss {rss} MB, code {response_code}", process,
+			"nginx: 3 processes (10,11,12), rss 42 MB, code 500"},
+		{"config count", "{check} count {count}", ping, "10.0.0.1 count 5"},
+		{"unknown kept", "{unknown}", process, "{unknown}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.ts, tt.c, "mail", "text"); got != tt.want {
+				t.Errorf("Parse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}